@@ -0,0 +1,238 @@
+package trmon
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// testAgent is an SNMPv2c agent serving an OID table on a random local UDP
+// port, in place of a real agent. Requests with another community are
+// dropped like a real agent does.
+type testAgent struct {
+	// Target is the address to give to NewHost, like "127.0.0.1:40123"
+	Target    string
+	community string
+	conn      net.PacketConn
+	mu        sync.Mutex
+	vars      []testVar
+}
+
+type testVar struct {
+	oid []int
+	pdu gosnmp.SnmpPDU
+}
+
+// newTestAgent serves the files of testdata/sample_oids until the test ends.
+func newTestAgent(t *testing.T, community string, files ...string) *testAgent {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	a := &testAgent{
+		Target:    conn.LocalAddr().String(),
+		community: community,
+		conn:      conn,
+	}
+	for _, f := range files {
+		if err := a.load("testdata/sample_oids/" + f); err != nil {
+			t.Fatalf("load %v: %v", f, err)
+		}
+	}
+	t.Cleanup(func() { conn.Close() })
+	go a.serve()
+	return a
+}
+
+// load reads lines of "oid;TYPE;value" as written by snmpwalk -Oq.
+func (a *testAgent) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if s.Text() == "" {
+			continue
+		}
+		f := strings.SplitN(s.Text(), ";", 3)
+		oid := numericOID(f[0])
+		if len(f) == 2 {
+			// an empty OCTET STRING has no type
+			f = append(f[:1], "STRING", f[1])
+		}
+		typ, v, err := parseTestValue(f[1], f[2])
+		if err != nil {
+			return fmt.Errorf("%v: %w", oid, err)
+		}
+		a.set(oid, typ, v)
+	}
+	return s.Err()
+}
+
+func parseTestValue(typ string, v string) (gosnmp.Asn1BER, interface{}, error) {
+	switch typ {
+	case "INTEGER":
+		n, err := strconv.Atoi(v)
+		return gosnmp.Integer, n, err
+	case "STRING":
+		return gosnmp.OctetString, []byte(strings.Trim(v, `"`)), nil
+	case "Hex-STRING":
+		b, err := hex.DecodeString(strings.ReplaceAll(v, " ", ""))
+		return gosnmp.OctetString, b, err
+	case "OID":
+		return gosnmp.ObjectIdentifier, numericOID(v), nil
+	case "Timeticks":
+		// "(14447638) 1 day, 16:07:56.38"
+		n, err := strconv.ParseUint(strings.Trim(strings.Fields(v)[0], "()"), 10, 32)
+		return gosnmp.TimeTicks, uint32(n), err
+	case "Counter32":
+		n, err := strconv.ParseUint(v, 10, 32)
+		return gosnmp.Counter32, uint32(n), err
+	case "Gauge32":
+		n, err := strconv.ParseUint(v, 10, 32)
+		return gosnmp.Gauge32, uint32(n), err
+	case "Counter64":
+		n, err := strconv.ParseUint(v, 10, 64)
+		return gosnmp.Counter64, n, err
+	}
+	return 0, nil, fmt.Errorf("unknown type %v", typ)
+}
+
+// set adds or replaces a variable, e.g. to advance a counter between polls.
+func (a *testAgent) set(oid string, typ gosnmp.Asn1BER, v interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	o := parseOID(oid)
+	i := sort.Search(len(a.vars), func(i int) bool { return compareOID(a.vars[i].oid, o) >= 0 })
+	pdu := gosnmp.SnmpPDU{Name: oid, Type: typ, Value: v}
+	if i < len(a.vars) && compareOID(a.vars[i].oid, o) == 0 {
+		a.vars[i].pdu = pdu
+		return
+	}
+	a.vars = append(a.vars, testVar{})
+	copy(a.vars[i+1:], a.vars[i:])
+	a.vars[i] = testVar{o, pdu}
+}
+
+func (a *testAgent) serve() {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil || req.Community != a.community {
+			continue
+		}
+		b, err := a.respond(req).MarshalMsg()
+		if err != nil {
+			continue
+		}
+		a.conn.WriteTo(b, addr)
+	}
+}
+
+func (a *testAgent) respond(req *gosnmp.SnmpPacket) *gosnmp.SnmpPacket {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	res := &gosnmp.SnmpPacket{
+		Version:   req.Version,
+		Community: req.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: req.RequestID,
+		Variables: make([]gosnmp.SnmpPDU, 0),
+	}
+	switch req.PDUType {
+	case gosnmp.GetRequest:
+		for _, v := range req.Variables {
+			res.Variables = append(res.Variables, a.get(v.Name))
+		}
+	case gosnmp.GetNextRequest:
+		for _, v := range req.Variables {
+			res.Variables = append(res.Variables, a.next(v.Name))
+		}
+	case gosnmp.GetBulkRequest:
+		repetitions := int(req.MaxRepetitions)
+		if repetitions == 0 {
+			repetitions = 10
+		}
+		for i, v := range req.Variables {
+			if i < int(req.NonRepeaters) {
+				res.Variables = append(res.Variables, a.next(v.Name))
+				continue
+			}
+			name := v.Name
+			for r := 0; r < repetitions; r++ {
+				pdu := a.next(name)
+				res.Variables = append(res.Variables, pdu)
+				if pdu.Type == gosnmp.EndOfMibView {
+					break
+				}
+				name = pdu.Name
+			}
+		}
+	}
+	return res
+}
+
+func (a *testAgent) get(oid string) gosnmp.SnmpPDU {
+	o := parseOID(oid)
+	i := sort.Search(len(a.vars), func(i int) bool { return compareOID(a.vars[i].oid, o) >= 0 })
+	if i < len(a.vars) && compareOID(a.vars[i].oid, o) == 0 {
+		return a.vars[i].pdu
+	}
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.NoSuchObject}
+}
+
+func (a *testAgent) next(oid string) gosnmp.SnmpPDU {
+	o := parseOID(oid)
+	i := sort.Search(len(a.vars), func(i int) bool { return compareOID(a.vars[i].oid, o) > 0 })
+	if i < len(a.vars) {
+		return a.vars[i].pdu
+	}
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView}
+}
+
+// numericOID replaces the top label of an OID like "iso.3.6.1" or "ccitt.0"
+// by its number.
+func numericOID(oid string) string {
+	oid = strings.Replace(oid, "iso", "1", 1)
+	oid = strings.Replace(oid, "ccitt", "0", 1)
+	return "." + oid
+}
+
+func parseOID(oid string) []int {
+	s := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	o := make([]int, len(s))
+	for i := range s {
+		o[i], _ = strconv.Atoi(s[i])
+	}
+	return o
+}
+
+// compareOID orders OIDs by their sub-identifiers like an agent walks them.
+func compareOID(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
@@ -0,0 +1,180 @@
+package trmon
+
+import (
+	"fmt"
+	"time"
+)
+
+// aggregateHostName is the virtual host holding aggregate interfaces. No
+// agent may be monitored by this name.
+const aggregateHostName = "aggregate"
+
+// AggregateConfig defines a virtual interface summing its members.
+//
+//	{"name": "tokyo-uplinks", "members": [{"host": "core1", "if": "ge-0/0/1"}]}
+type AggregateConfig struct {
+	Name    string         `json:"name"`
+	Members []MemberConfig `json:"members"`
+}
+
+// MemberConfig is a member interface given by ifIndex, or by ifDescr or
+// ifName when Index is 0.
+type MemberConfig struct {
+	Host  string `json:"host"`
+	IF    string `json:"if,omitempty"`
+	Index int    `json:"index,omitempty"`
+}
+
+// Aggregate is a virtual interface whose counters are the sum of the
+// counters of its members. It is an IF of the aggregate host, so it is
+// sorted, filtered, marked and exported like any other interface.
+type Aggregate struct {
+	AggregateConfig
+	ifc *IF
+	// fromConfig is set for aggregates of the config file, which are not
+	// kept in the state file
+	fromConfig bool
+}
+
+// newAggregateHost returns the aggregate host, which has no collector as it
+// is never polled.
+func newAggregateHost(l *Logger) *Host {
+	h := newCollectorHost(aggregateHostName, nil, l)
+	h.Group = aggregateHostName
+	h.Reachable = true
+	h.virtual = true
+	return h
+}
+
+func (m MemberConfig) match(h *Host, i *IF) bool {
+	if h.Name != m.Host {
+		return false
+	}
+	if m.Index != 0 {
+		return i.Index == m.Index
+	}
+	return i.Desc == m.IF || i.Name == m.IF
+}
+
+// update sums the counters of members found in hosts.
+func (ag *Aggregate) update(hosts []*Host) {
+	sum := ag.ifc.counters()
+	for _, c := range sum {
+		c.Last, c.Diff, c.Rate = 0, 0, 0
+		c.LastTime = time.Time{}
+	}
+	ag.ifc.Speed = 0
+	up, found := 0, 0
+	for _, m := range ag.Members {
+		for _, h := range hosts {
+			if h.virtual {
+				continue
+			}
+			for _, i := range h.IFs {
+				if !m.match(h, i) {
+					continue
+				}
+				found++
+				if i.OperStatus == "UP" {
+					up++
+				}
+				ag.ifc.Speed += i.Speed
+				for n, c := range i.counters() {
+					sum[n].Last += c.Last
+					sum[n].Diff += c.Diff
+					sum[n].Rate += c.Rate
+					if c.LastTime.After(sum[n].LastTime) {
+						sum[n].LastTime = c.LastTime
+					}
+				}
+			}
+		}
+	}
+
+	switch {
+	case found == 0:
+		ag.ifc.OperStatus = ""
+	case up == 0:
+		ag.ifc.OperStatus = "Down"
+	default:
+		ag.ifc.OperStatus = "UP"
+	}
+	ag.ifc.Alias = fmt.Sprintf("%v/%v members up", up, len(ag.Members))
+}
+
+// updateAggregates refreshes aggregate interfaces before they are displayed.
+func (m *MainWidget) updateAggregates() {
+	for _, ag := range m.aggregates {
+		ag.update(m.Hosts)
+	}
+}
+
+// aggregateConfigs returns the aggregates defined while running, to keep
+// in the state file.
+func (m *MainWidget) aggregateConfigs() []AggregateConfig {
+	configs := make([]AggregateConfig, 0, len(m.aggregates))
+	for _, ag := range m.aggregates {
+		if ag.fromConfig {
+			continue
+		}
+		configs = append(configs, ag.AggregateConfig)
+	}
+	return configs
+}
+
+// addAggregate adds an aggregate interface, creating the aggregate host
+// on first use. fromConfig tells an aggregate of the config file.
+func (a *App) addAggregate(ac AggregateConfig, fromConfig bool) error {
+	if ac.Name == "" || len(ac.Members) == 0 {
+		return fmt.Errorf("aggregate needs a name and members")
+	}
+	for _, ag := range a.mw.aggregates {
+		if ag.Name == ac.Name {
+			return fmt.Errorf("aggregate %v already exists", ac.Name)
+		}
+	}
+	if a.aggHost == nil {
+		a.aggHost = newAggregateHost(a.log)
+		a.hosts = append(a.hosts, a.aggHost)
+		a.mw.Hosts = a.hosts
+	}
+	index := 1
+	for ; a.aggHost.IFs[index] != nil; index++ {
+	}
+	ifc := NewIF(index, a.log)
+	ifc.Desc = ac.Name
+	ifc.AdminStatus = "UP"
+	a.aggHost.IFs[index] = ifc
+	a.mw.aggregates = append(a.mw.aggregates, &Aggregate{ac, ifc, fromConfig})
+	a.log.Info().Msgf("add aggregate %v with %v members", ac.Name, len(ac.Members))
+	return nil
+}
+
+// aggregateMarked defines an aggregate from the marked interfaces.
+func (a *App) aggregateMarked(name string) error {
+	members := make([]MemberConfig, 0, len(a.mw.Markeds))
+	for _, k := range a.mw.Markeds {
+		r, ok := a.mw.lookup(k)
+		if !ok || r.host.virtual {
+			continue
+		}
+		members = append(members, MemberConfig{Host: k.Host, IF: r.ifc.Desc, Index: k.Index})
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no marked interface to aggregate")
+	}
+	return a.addAggregate(AggregateConfig{Name: name, Members: members}, false)
+}
+
+func (a *App) removeAggregate(name string) error {
+	for i, ag := range a.mw.aggregates {
+		if ag.Name != name {
+			continue
+		}
+		delete(a.aggHost.IFs, ag.ifc.Index)
+		a.mw.aggregates = append(a.mw.aggregates[:i], a.mw.aggregates[i+1:]...)
+		a.log.Info().Msgf("remove aggregate %v", name)
+		return nil
+	}
+	return fmt.Errorf("aggregate %v does not exist", name)
+}
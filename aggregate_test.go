@@ -0,0 +1,91 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestAggregate_update(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	core1 := testHost("core1", l)
+	core2 := testHost("core2", l)
+	core2.IFs[1].OperStatus = "Down"
+	a := &App{log: l, hosts: []*Host{core1, core2}}
+	a.mw = NewMainWidget("main", a.hosts, NewNarrowWidget("filter", "", l), l)
+
+	err := a.addAggregate(AggregateConfig{
+		Name: "uplinks",
+		Members: []MemberConfig{
+			{Host: "core1", IF: "eth0"},
+			{Host: "core2", Index: 1},
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("App.addAggregate() error = %v", err)
+	}
+	if err := a.addAggregate(AggregateConfig{Name: "uplinks", Members: []MemberConfig{{Host: "core1", Index: 2}}}, false); err == nil {
+		t.Errorf("App.addAggregate() accepts a duplicated name")
+	}
+	if len(a.mw.Hosts) != 3 || !a.mw.Hosts[2].virtual {
+		t.Fatalf("MainWidget.Hosts = %v, want the aggregate host appended", a.mw.Hosts)
+	}
+
+	a.mw.updateAggregates()
+	ifc := a.aggHost.IFs[1]
+	if ifc.InOctets.Rate != 20 || ifc.OutOctets.Rate != 60 {
+		t.Errorf("aggregate rate = %v, %v, want 20, 60", ifc.InOctets.Rate, ifc.OutOctets.Rate)
+	}
+	if ifc.OperStatus != "UP" || ifc.Alias != "1/2 members up" {
+		t.Errorf("aggregate status = %v %q, want UP with 1/2 members up", ifc.OperStatus, ifc.Alias)
+	}
+
+	// Rates are recomputed, not accumulated
+	a.mw.updateAggregates()
+	if ifc.InOctets.Rate != 20 {
+		t.Errorf("aggregate rate after second update = %v, want 20", ifc.InOctets.Rate)
+	}
+
+	if err := a.removeAggregate("uplinks"); err != nil {
+		t.Errorf("App.removeAggregate() error = %v", err)
+	}
+	if len(a.aggHost.IFs) != 0 || len(a.mw.aggregates) != 0 {
+		t.Errorf("aggregate remains after App.removeAggregate()")
+	}
+}
+
+func TestApp_aggregateMarked(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	a := &App{log: l, hosts: []*Host{testHost("core1", l)}}
+	a.mw = NewMainWidget("main", a.hosts, NewNarrowWidget("filter", "", l), l)
+
+	if err := a.aggregateMarked("none"); err == nil {
+		t.Errorf("App.aggregateMarked() without marks error = nil")
+	}
+	a.mw.Markeds = []ifKey{{"core1", 2}, {"core1", 3}}
+	if err := a.aggregateMarked("lag"); err != nil {
+		t.Fatalf("App.aggregateMarked() error = %v", err)
+	}
+	a.mw.updateAggregates()
+	if got := a.aggHost.IFs[1].InOctets.Rate; got != 50 {
+		t.Errorf("aggregate rate = %v, want 50", got)
+	}
+}
+
+func TestMainWidget_aggregateConfigs(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	a := &App{log: l, hosts: []*Host{testHost("core1", l)}}
+	a.mw = NewMainWidget("main", a.hosts, NewNarrowWidget("filter", "", l), l)
+
+	file := AggregateConfig{Name: "file", Members: []MemberConfig{{Host: "core1", Index: 2}}}
+	runtime := AggregateConfig{Name: "runtime", Members: []MemberConfig{{Host: "core1", Index: 3}}}
+	if err := a.addAggregate(file, true); err != nil {
+		t.Fatalf("App.addAggregate() error = %v", err)
+	}
+	if err := a.addAggregate(runtime, false); err != nil {
+		t.Fatalf("App.addAggregate() error = %v", err)
+	}
+	if got := a.mw.aggregateConfigs(); !reflect.DeepEqual(got, []AggregateConfig{runtime}) {
+		t.Errorf("MainWidget.aggregateConfigs() = %v, want only %v", got, runtime)
+	}
+}
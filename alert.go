@@ -0,0 +1,80 @@
+package trmon
+
+import "fmt"
+
+// alertRule shows interfaces matching a filter expression in red, e.g.
+// "in_bcast>1k" to spot a broadcast storm.
+type alertRule struct {
+	expr  string
+	match filterFunc
+	// fromConfig is set for alerts of the config file, which are not kept
+	// in the state file
+	fromConfig bool
+}
+
+// addAlert adds the alert of expr. fromConfig tells an alert of the config
+// file.
+func (m *MainWidget) addAlert(expr string, fromConfig bool) error {
+	if expr == "" {
+		return fmt.Errorf("alert needs an expression")
+	}
+	for _, a := range m.alerts {
+		if a.expr == expr {
+			return fmt.Errorf("alert %v already exists", expr)
+		}
+	}
+	f, err := parseFilter(expr)
+	if err != nil {
+		return err
+	}
+	m.alerts = append(m.alerts, alertRule{expr, f, fromConfig})
+	m.log.Info().Msgf("add alert %v", expr)
+	return nil
+}
+
+// removeAlert removes the alert of expr, or every alert when expr is empty.
+func (m *MainWidget) removeAlert(expr string) error {
+	if expr == "" {
+		m.alerts = m.alerts[:0]
+		return nil
+	}
+	for i, a := range m.alerts {
+		if a.expr == expr {
+			m.alerts = append(m.alerts[:i], m.alerts[i+1:]...)
+			m.log.Info().Msgf("remove alert %v", expr)
+			return nil
+		}
+	}
+	return fmt.Errorf("alert %v does not exist", expr)
+}
+
+// alerting returns the expressions of the alerts the row matches.
+func (m *MainWidget) alerting(r ifRow) []string {
+	var exprs []string
+	for _, a := range m.alerts {
+		if a.match(r) {
+			exprs = append(exprs, a.expr)
+		}
+	}
+	return exprs
+}
+
+func (m *MainWidget) alertExprs() []string {
+	exprs := make([]string, 0, len(m.alerts))
+	for _, a := range m.alerts {
+		exprs = append(exprs, a.expr)
+	}
+	return exprs
+}
+
+// runtimeAlerts returns the expressions of the alerts added while running,
+// to keep in the state file.
+func (m *MainWidget) runtimeAlerts() []string {
+	exprs := make([]string, 0, len(m.alerts))
+	for _, a := range m.alerts {
+		if !a.fromConfig {
+			exprs = append(exprs, a.expr)
+		}
+	}
+	return exprs
+}
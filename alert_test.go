@@ -0,0 +1,86 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMainWidget_alerting(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := testHost("core1", l)
+	h.IFs[2].InBcastPkts.Rate = 5000
+	m := NewMainWidget("main", []*Host{h}, NewNarrowWidget("filter", "", l), l)
+
+	for _, expr := range []string{"in_bcast>1k", "if=eth1 and in>100"} {
+		if err := m.addAlert(expr, false); err != nil {
+			t.Fatalf("MainWidget.addAlert(%q) error = %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"in_bcast>1k", "", "in_bcast>"} {
+		if err := m.addAlert(expr, false); err == nil {
+			t.Errorf("MainWidget.addAlert(%q) accepts it", expr)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		index int
+		want  []string
+	}{
+		{
+			name:  "no alert",
+			index: 1,
+			want:  nil,
+		},
+		{
+			name:  "both alerts",
+			index: 2,
+			want:  []string{"in_bcast>1k", "if=eth1 and in>100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.alerting(ifRow{h, h.IFs[tt.index]}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MainWidget.alerting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := m.removeAlert("in_bcast>1k"); err != nil {
+		t.Fatalf("MainWidget.removeAlert() error = %v", err)
+	}
+	if got := m.alertExprs(); !reflect.DeepEqual(got, []string{"if=eth1 and in>100"}) {
+		t.Errorf("MainWidget.alertExprs() = %v after removal", got)
+	}
+	if err := m.removeAlert(""); err != nil || len(m.alerts) != 0 {
+		t.Errorf("MainWidget.removeAlert(\"\") = %v, alerts = %v, want all removed", err, m.alerts)
+	}
+}
+
+func TestMainWidget_formatRow_breakdown(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := testHost("core1", l)
+	i := h.IFs[1]
+	i.InUcastPkts.Rate, i.InMcastPkts.Rate, i.InBcastPkts.Rate = 1000, 20, 3
+	m := NewMainWidget("main", []*Host{h}, NewNarrowWidget("filter", "", l), l)
+	m.displaybps = false
+	m.setUnit(Pps)
+
+	if got := m.formatRow(ifRow{h, i})[3]; got != "1,023" {
+		t.Errorf("IN = %v, want the sum of every packet", got)
+	}
+	m.breakdown = true
+	if got := m.formatRow(ifRow{h, i})[3]; got != "1,000/20/3" {
+		t.Errorf("IN = %v, want ucast/mcast/bcast", got)
+	}
+	if got := m.unitLabel(); got != "pps u/m/b" {
+		t.Errorf("MainWidget.unitLabel() = %v", got)
+	}
+	// bps is never broken down
+	m.displaybps = true
+	m.setUnit(Bps)
+	if got := m.unitLabel(); got != "bps" {
+		t.Errorf("MainWidget.unitLabel() = %v", got)
+	}
+}
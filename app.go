@@ -5,34 +5,148 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jroimartin/gocui"
 )
 
+const (
+	retryMinBackoff = 2 * time.Second
+	retryMaxBackoff = 5 * time.Minute
+)
+
 type App struct {
-	hosts []*Host
-	gui   *gocui.Gui
-	log   *Logger
+	hosts    []*Host
+	gui      *gocui.Gui
+	log      *Logger
+	mw       *MainWidget
+	ctx      context.Context
+	cancels  map[*Host]context.CancelFunc
+	interval int64
+	// flagInterval is the interval of -i, used when the config file
+	// gives none
+	flagInterval int
+	// fastInterval is of the fast tier, 0 disables it
+	fastInterval time.Duration
+	community    string
+	cdp          bool
+	dot3         bool
+	optics       bool
+	// domProfiles are the vendor DOM profiles of the config file by name
+	domProfiles map[string]*DOMProfile
+	// healthProfiles are the builtin and configured health profiles by name
+	healthProfiles map[string]*HealthProfile
+	// aggHost holds aggregate interfaces once one is defined
+	aggHost *Host
+	// snmp is the global SNMP options overridden by those of each agent
+	snmp SNMPOptions
+	// slots bounds the polls running at once, and polling counts them
+	slots   chan struct{}
+	polling int64
+	// pollers are the polling goroutines of startHost
+	pollers sync.WaitGroup
+	// deadline is when the lifespan ends, zero when unlimited
+	deadline time.Time
+	// fileConfig is the configuration file as last read from configFile
+	configFile string
+	fileConfig *FileConfig
 }
 
 type Config struct {
-	Interval  int
+	Interval int
+	// FastInterval polls marked interfaces and the one of the detail view
+	// with targeted GETs in between full polls. 0 disables it.
+	FastInterval time.Duration
+	// Lifespan ends trmon after that many seconds. 0 means unlimited.
 	Lifespan  int
 	Community string
 	Expr      string
 	IsDebug   bool
 	Output    io.Writer
+	// StateFile keeps marks and display settings between sessions.
+	// Empty disables it.
+	StateFile string
+	// ConfigFile is read at startup in addition to the agents given to Run.
+	ConfigFile string
+	// Hosts are monitored in addition to the agents given to Run, e.g.
+	// hosts of another backend with HostConfig.Collector.
+	Hosts []HostConfig
+	// CDP walks CISCO-CDP-MIB of every agent for neighbors.
+	CDP bool
+	// Dot3 walks dot3StatsTable of every agent for the error breakdown.
+	Dot3 bool
+	// Optics walks ENTITY-SENSOR-MIB of every agent for DOM readings.
+	Optics bool
+	// HostLabel and IFLabel choose the Name and I/F columns. Empty keeps
+	// the ones of the state file.
+	HostLabel string
+	IFLabel   string
+	// Simulate adds hosts of SimulateIFs interfaces with fabricated
+	// traffic, for demos and load tests.
+	Simulate    int
+	SimulateIFs int
+	// MaxPolls is how many agents are polled at once. 0 means no limit.
+	MaxPolls int
+	// Timeout is the SNMP timeout in seconds, and the others are the
+	// SNMP options of every agent. See SNMPOptions.
+	Timeout        int
+	Retries        int
+	MaxRepetitions int
+	NonRepeaters   int
+	AutoTune       bool
 }
 
 func (a *App) Run(hostnames []string, c *Config) error {
 
 	a.hosts = make([]*Host, 0)
 	a.log = NewLogger(c.IsDebug, c.Output)
+	a.cancels = make(map[*Host]context.CancelFunc)
+	a.interval = int64(c.Interval)
+	a.flagInterval = c.Interval
+	a.fastInterval = c.FastInterval
+	a.community = c.Community
+	a.cdp = c.CDP
+	a.dot3 = c.Dot3
+	a.optics = c.Optics
+	retries := c.Retries
+	a.snmp = SNMPOptions{
+		Timeout:        time.Duration(c.Timeout) * time.Second,
+		Retries:        &retries,
+		MaxRepetitions: c.MaxRepetitions,
+		NonRepeaters:   c.NonRepeaters,
+		AutoTune:       c.AutoTune,
+	}
+	if c.MaxPolls > 0 {
+		a.slots = make(chan struct{}, c.MaxPolls)
+	}
+	a.configFile = c.ConfigFile
+	a.fileConfig = new(FileConfig)
+	if c.ConfigFile != "" {
+		fc, err := LoadConfigFile(c.ConfigFile)
+		if err != nil {
+			a.log.Error().Msgf("failed to load config %v: %v", c.ConfigFile, err)
+			return err
+		}
+		a.fileConfig = fc
+		if fc.Interval > 0 {
+			a.interval = int64(fc.Interval)
+		}
+	}
+	a.setProfiles(a.fileConfig)
+
+	hosts := append(make([]HostConfig, 0, len(hostnames)), a.fileConfig.Hosts...)
+	hosts = append(hosts, c.Hosts...)
+	for _, name := range hostnames {
+		hosts = append(hosts, ParseAgent(name))
+	}
+	hosts = append(hosts, SimulatedHosts(c.Simulate, c.SimulateIFs, a.log)...)
 
 	// SNMP host Initalize
 	a.log.Debug().Msg("SNMP host init")
-	if err := a.initHosts(hostnames, c.Community); err != nil {
+	if err := a.initHosts(hosts); err != nil {
 		return err
 	}
 	// CUI Initialize
@@ -45,17 +159,65 @@ func (a *App) Run(hostnames []string, c *Config) error {
 	}
 	defer a.gui.Close()
 
-	if err := a.initCUI(c.Expr); err != nil {
+	st := a.loadState(c.StateFile)
+	expr := c.Expr
+	if expr == "" {
+		expr = a.fileConfig.Filter
+	}
+	if expr == "" && st != nil {
+		expr = st.Filter
+	}
+	if err := a.initCUI(expr); err != nil {
 		a.log.Error().Msgf("%v", err)
 		return err
 	}
+	a.mw.thresholds = a.fileConfig.opticThresholds()
+	if st != nil {
+		a.mw.restore(st)
+	}
+	if c.HostLabel != "" {
+		if err := a.mw.setHostLabel(c.HostLabel); err != nil {
+			return err
+		}
+	}
+	if c.IFLabel != "" {
+		if err := a.mw.setIFLabel(c.IFLabel); err != nil {
+			return err
+		}
+	}
+	for _, ac := range a.fileConfig.Aggregates {
+		if err := a.addAggregate(ac, true); err != nil {
+			a.log.Debug().Msgf("skip aggregate %v: %v", ac.Name, err)
+		}
+	}
+	if st != nil {
+		for _, ac := range st.Aggregates {
+			if err := a.addAggregate(ac, false); err != nil {
+				a.log.Debug().Msgf("skip aggregate %v: %v", ac.Name, err)
+			}
+		}
+	}
+	for _, expr := range a.fileConfig.Alerts {
+		if err := a.mw.addAlert(expr, true); err != nil {
+			a.log.Debug().Msgf("skip alert %v: %v", expr, err)
+		}
+	}
+	if st != nil {
+		for _, expr := range st.Alerts {
+			if err := a.mw.addAlert(expr, false); err != nil {
+				a.log.Debug().Msgf("skip alert %v: %v", expr, err)
+			}
+		}
+	}
+	defer a.saveState(c.StateFile)
 
 	a.log.Debug().Msg("Start background goroutin")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	a.handleSignals(ctx)
 	a.suicide(ctx, c.Lifespan)
-	a.updateHosts(ctx, c.Interval)
+	a.updateHosts(ctx)
 	a.showInitView(ctx, c.Interval)
 
 	// mainloop for CUI Event
@@ -67,32 +229,111 @@ func (a *App) Run(hostnames []string, c *Config) error {
 	return nil
 }
 
-func (a *App) initHosts(hostnames []string, community string) error {
+// initHosts discovers the hosts before the TUI starts. Unreachable hosts
+// are kept and retried in the background, so it only fails without hosts.
+func (a *App) initHosts(hosts []HostConfig) error {
 	// SNMP host Initalize
 	a.log.Debug().Msg("SNMP host init")
-	for _, name := range hostnames {
-		host, err := NewHost(name, community, a.log)
-		if err != nil {
-			a.log.Warn().Msgf("%v can't initalized : %v", name, err)
+	if len(hosts) == 0 {
+		return errors.New("No host to monitor")
+	}
+	var reachable int64
+	var wg sync.WaitGroup
+	for _, hc := range hosts {
+		if hc.Name == aggregateHostName {
+			a.log.Warn().Msgf("skip host %v: the name is of the aggregate host", hc.Name)
 			continue
 		}
+		host := newCollectorHost(hc.Name, a.collectorOf(hc), a.log)
+		host.Group = hc.Group
 		a.hosts = append(a.hosts, host)
+		wg.Add(1)
+		go func(h *Host) {
+			defer wg.Done()
+			var err error
+			a.poll(context.Background(), h, func() { err = h.discover() })
+			if err != nil {
+				// Keep the host so that it is retried in the background
+				a.log.Warn().Msgf("%v can't initalized, retry later : %v", h.Name, err)
+				return
+			}
+			atomic.AddInt64(&reachable, 1)
+		}(host)
+	}
+	wg.Wait()
+	// The UI is not running yet, so the hosts are shown as discovered
+	for _, h := range a.hosts {
+		h.apply()
 	}
 
-	if len(a.hosts) == 0 {
-		a.log.Warn().Msgf("No accesstable host")
-		return errors.New("No accesstable host")
+	if reachable == 0 {
+		a.log.Warn().Msgf("No accesstable host yet, retry later")
 	}
 	return nil
 }
 
+// setProfiles replaces the DOM and health profiles by those of fc.
+func (a *App) setProfiles(fc *FileConfig) {
+	a.domProfiles = make(map[string]*DOMProfile)
+	a.healthProfiles = make(map[string]*HealthProfile)
+	for i := range builtinHealthProfiles {
+		a.healthProfiles[builtinHealthProfiles[i].Name] = &builtinHealthProfiles[i]
+	}
+	for i := range fc.DOMProfiles {
+		a.domProfiles[fc.DOMProfiles[i].Name] = &fc.DOMProfiles[i]
+	}
+	for i := range fc.HealthProfiles {
+		a.healthProfiles[fc.HealthProfiles[i].Name] = &fc.HealthProfiles[i]
+	}
+}
+
+// collectorOf returns the collector of the agent with its options applied.
+func (a *App) collectorOf(hc HostConfig) Collector {
+	if hc.Collector != nil {
+		return hc.Collector
+	}
+	if isLocal(hc.Name) {
+		return NewLocalCollector("/", a.log)
+	}
+	c := NewSNMPCollector(hc.Name, a.communityOf(hc), a.log)
+	c.SetOptions(a.snmp.override(hc.snmpOptions()))
+	c.CDP = a.cdp || hc.CDP
+	c.Dot3 = a.dot3 || hc.Dot3
+	c.Optics = a.optics || hc.Optics
+	if hc.DOMProfile != "" {
+		p, ok := a.domProfiles[hc.DOMProfile]
+		if !ok {
+			a.log.Warn().Msgf("%v: unknown DOM profile %v", hc.Name, hc.DOMProfile)
+		}
+		c.DOMProfile = p
+	}
+	if hc.HealthProfile != "" {
+		p, ok := a.healthProfiles[hc.HealthProfile]
+		if !ok {
+			a.log.Warn().Msgf("%v: unknown health profile %v", hc.Name, hc.HealthProfile)
+		}
+		c.HealthProfile = p
+	}
+	return c
+}
+
+func (a *App) communityOf(hc HostConfig) string {
+	if hc.Community == "" {
+		return a.community
+	}
+	return hc.Community
+}
+
 func (a *App) initCUI(expr string) error {
 	a.gui.Cursor = true
 	a.gui.Highlight = true
-	nw := NewNarrowWidget("regexp", expr, a.log)
+	nw := NewNarrowWidget("filter", expr, a.log)
 	mw := NewMainWidget("main", a.hosts, nw, a.log)
+	cw := NewCommandWidget("cmdline", a.commands(mw, nw), a.log)
+	a.mw = mw
+	mw.status = a.status
 	a.gui.SetManager(mw, nw)
-	setKeybindgings(a.gui, mw, nw)
+	setKeybindgings(a.gui, a, mw, nw, cw)
 
 	// View Initialize
 	maxX, maxY := a.gui.Size()
@@ -102,46 +343,209 @@ func (a *App) initCUI(expr string) error {
 	}
 	a.gui.SetCurrentView("main")
 	v.SetCursor(0, 0)
-	_, err1 := a.newView("regexp", 0, maxY-2, maxX-2, maxY, expr)
+	_, err1 := a.newView("filter", 0, maxY-2, maxX-2, maxY, expr)
 	if err1 != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *App) updateHosts(ctx context.Context, interval int) {
-	ticker := time.NewTicker(time.Duration(interval) * time.Second)
-	for _, host := range a.hosts {
-		h := host
-		c := context.Context(ctx)
-		go func(ctx context.Context, h *Host) {
-			a.log.Debug().Msgf("First Update %v", h.Name)
-			h.Update()
-			for {
-				select {
-				case <-ticker.C:
-					a.log.Debug().Msgf("Update %v", h.Name)
-					h.Update()
-					a.log.Debug().Msg("Update Display")
-					a.gui.Update(func(g *gocui.Gui) error { return nil })
-				case <-ctx.Done():
-					a.log.Debug().Msgf("Done update %v goroutine", h.Name)
+func (a *App) updateHosts(ctx context.Context) {
+	a.ctx = ctx
+	for _, h := range a.hosts {
+		a.startHost(h)
+	}
+}
+
+// startHost starts the polling goroutine of h. It is stopped by removeHost
+// or when the App context is done.
+func (a *App) startHost(h *Host) {
+	if h.virtual {
+		return
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.cancels[h] = cancel
+	a.pollers.Add(1)
+	go func(ctx context.Context, h *Host) {
+		defer a.pollers.Done()
+		defer h.close()
+		if !h.reachable && !a.retryHost(ctx, h) {
+			return
+		}
+		a.log.Debug().Msgf("First Update %v", h.Name)
+		if !a.poll(ctx, h, h.Update) {
+			return
+		}
+		interval := a.pollInterval()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var fast <-chan time.Time
+		if a.fastInterval > 0 {
+			t := time.NewTicker(a.fastInterval)
+			defer t.Stop()
+			fast = t.C
+		}
+		for {
+			select {
+			case <-ticker.C:
+				if d := a.pollInterval(); d != interval {
+					interval = d
+					ticker.Reset(interval)
+				}
+				a.log.Debug().Msgf("Update %v", h.Name)
+				if !a.poll(ctx, h, h.Update) {
 					return
 				}
+				a.log.Debug().Msg("Update Display")
+				a.gui.Update(func(g *gocui.Gui) error { return nil })
+			case <-fast:
+				if indexes := a.mw.fast.of(h.Name); len(indexes) > 0 {
+					if !a.poll(ctx, h, func() { h.updateFast(indexes) }) {
+						return
+					}
+					a.gui.Update(func(g *gocui.Gui) error { return nil })
+				}
+			case <-ctx.Done():
+				a.log.Debug().Msgf("Done update %v goroutine", h.Name)
+				return
+			}
+		}
+	}(ctx, h)
+}
+
+func (a *App) pollInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&a.interval)) * time.Second
+}
+
+// setInterval changes the polling interval. Running goroutines pick it up
+// on their next tick.
+func (a *App) setInterval(sec int) error {
+	if sec < 1 {
+		return fmt.Errorf("Too short interval, The minimum SNMP polling interval is 1 second")
+	}
+	atomic.StoreInt64(&a.interval, int64(sec))
+	a.log.Info().Msgf("set interval %v sec", sec)
+	return nil
+}
+
+// addHost adds an agent while running. Discovery happens in the polling
+// goroutine, so the host is shown as unreachable until it answers.
+func (a *App) addHost(hc HostConfig) error {
+	if hc.Name == aggregateHostName {
+		return fmt.Errorf("%v is the name of the aggregate host", hc.Name)
+	}
+	for _, h := range a.hosts {
+		if h.Name == hc.Name {
+			return fmt.Errorf("%v is already monitored", hc.Name)
+		}
+	}
+	h := newCollectorHost(hc.Name, a.collectorOf(hc), a.log)
+	h.Group = hc.Group
+	a.hosts = append(a.hosts, h)
+	a.mw.Hosts = a.hosts
+	a.startHost(h)
+	a.log.Info().Msgf("add host %v", hc.Name)
+	return nil
+}
+
+// removeHost stops polling the agent and forgets its interfaces and marks.
+func (a *App) removeHost(name string) error {
+	for i, h := range a.hosts {
+		if h.Name != name {
+			continue
+		}
+		if h.virtual {
+			return fmt.Errorf("%v is not an agent, use unaggregate", name)
+		}
+		if cancel, ok := a.cancels[h]; ok {
+			cancel()
+			delete(a.cancels, h)
+		}
+		a.hosts = append(a.hosts[:i], a.hosts[i+1:]...)
+		a.mw.Hosts = a.hosts
+		a.mw.unmarkHost(name)
+		a.log.Info().Msgf("remove host %v", name)
+		return nil
+	}
+	return fmt.Errorf("%v is not monitored", name)
+}
+
+// replaceHost polls an agent anew with the options of hc, keeping its
+// place and marks. The interfaces are discovered again.
+func (a *App) replaceHost(hc HostConfig) error {
+	for i, h := range a.hosts {
+		if h.Name != hc.Name || h.virtual {
+			continue
+		}
+		if cancel, ok := a.cancels[h]; ok {
+			cancel()
+			delete(a.cancels, h)
+		}
+		nh := newCollectorHost(hc.Name, a.collectorOf(hc), a.log)
+		nh.Group = hc.Group
+		a.hosts[i] = nh
+		a.mw.Hosts = a.hosts
+		a.startHost(nh)
+		a.log.Info().Msgf("replace host %v", hc.Name)
+		return nil
+	}
+	return fmt.Errorf("%v is not monitored", hc.Name)
+}
+
+// retryHost tries to discover h with exponential backoff until it succeeds
+// or ctx is done. It reports whether h became reachable.
+func (a *App) retryHost(ctx context.Context, h *Host) bool {
+	wait := time.Duration(0)
+	backoff := retryMinBackoff
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		select {
+		case <-time.After(wait):
+			a.log.Debug().Msgf("Try initialize %v", h.Name)
+			var err error
+			if !a.poll(ctx, h, func() { err = h.discover() }) {
+				return false
+			}
+			if err != nil {
+				a.log.Debug().Msgf("%v is still unreachable : %v", h.Name, err)
+				wait = backoff
+				backoff *= 2
+				if backoff > retryMaxBackoff {
+					backoff = retryMaxBackoff
+				}
+				continue
 			}
-		}(c, h)
+			a.log.Info().Msgf("%v became reachable", h.Name)
+			return true
+		case <-ctx.Done():
+			a.log.Debug().Msgf("Done retry %v goroutine", h.Name)
+			return false
+		}
 	}
 }
 
+// suicide ends trmon after lifespan seconds unless it is 0 or less, and
+// redraws the time left in the meantime.
 func (a *App) suicide(ctx context.Context, lifespan int) {
+	if lifespan <= 0 {
+		return
+	}
+	a.deadline = time.Now().Add(time.Duration(lifespan) * time.Second)
 	after := time.After(time.Duration(lifespan) * time.Second)
+	// redraw the countdown of the title every second
+	ticker := time.NewTicker(time.Second)
 	c := context.Context(ctx)
 	go func() {
+		defer ticker.Stop()
 		for {
 			select {
+			case <-ticker.C:
+				a.gui.Update(func(g *gocui.Gui) error { return nil })
 			case <-after:
 				a.log.Info().Msgf("It has been running for more than %v seconds, so it will end the process.", lifespan)
-				a.gui.Update(func(g *gocui.Gui) error { return gocui.ErrQuit })
+				a.quit()
 				return
 			case <-c.Done():
 				a.log.Debug().Msg("Done suicide goroutine")
@@ -151,6 +555,20 @@ func (a *App) suicide(ctx context.Context, lifespan int) {
 	}()
 }
 
+// status is the title of the main view, the polls and the time left of
+// the lifespan.
+func (a *App) status() string {
+	s := a.pollStatus()
+	if !a.deadline.IsZero() {
+		left := time.Until(a.deadline)
+		if left < 0 {
+			left = 0
+		}
+		s += fmt.Sprintf(", quit in %v", left.Truncate(time.Second))
+	}
+	return s
+}
+
 func (a *App) showInitView(ctx context.Context, lifespan int) error {
 	timeout := time.After(time.Duration(lifespan) * time.Second)
 	ticker := time.NewTicker(time.Duration(1) * time.Second)
@@ -189,6 +607,32 @@ func (a *App) showInitView(ctx context.Context, lifespan int) error {
 	return nil
 }
 
+func (a *App) loadState(path string) *State {
+	if path == "" {
+		return nil
+	}
+	st, err := loadState(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.log.Warn().Msgf("failed to load state %v: %v", path, err)
+		}
+		return nil
+	}
+	a.log.Debug().Msgf("load state %v", path)
+	return st
+}
+
+func (a *App) saveState(path string) {
+	if path == "" || a.mw == nil {
+		return
+	}
+	if err := saveState(path, a.mw.state()); err != nil {
+		a.log.Warn().Msgf("failed to save state %v: %v", path, err)
+		return
+	}
+	a.log.Debug().Msgf("save state %v", path)
+}
+
 func (a *App) newView(name string, x0, y0, x1, y1 int, msg string) (*gocui.View, error) {
 	v, err := a.gui.SetView(name, x0, y0, x1, y1)
 	if err != nil {
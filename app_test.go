@@ -1,27 +1,30 @@
 package trmon
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/jroimartin/gocui"
 )
 
 func TestApp_initHosts(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
 	type fields struct {
 		hosts []*Host
 		gui   *gocui.Gui
 		log   *Logger
 	}
 	type args struct {
-		hostnames []string
-		community string
+		hosts []HostConfig
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
+		name      string
+		fields    fields
+		args      args
+		wantErr   bool
+		wantHosts int
 	}{
 		// TODO: Add test cases.
 		{
@@ -32,10 +35,10 @@ func TestApp_initHosts(t *testing.T) {
 				log:   NewLogger(true, os.Stdout),
 			},
 			args: args{
-				hostnames: []string{},
-				community: "my_comm",
+				hosts: []HostConfig{},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantHosts: 0,
 		},
 		{
 			name: "invalid community",
@@ -45,10 +48,10 @@ func TestApp_initHosts(t *testing.T) {
 				log:   NewLogger(true, os.Stdout),
 			},
 			args: args{
-				hostnames: []string{"127.0.0.1"},
-				community: "mogear",
+				hosts: []HostConfig{{Name: agent.Target, Community: "mogear"}},
 			},
-			wantErr: true,
+			wantErr:   false,
+			wantHosts: 1,
 		},
 		{
 			name: "unreachable host",
@@ -58,10 +61,10 @@ func TestApp_initHosts(t *testing.T) {
 				log:   NewLogger(true, os.Stdout),
 			},
 			args: args{
-				hostnames: []string{"127.0.0.254"},
-				community: "my_comm",
+				hosts: []HostConfig{{Name: "127.0.0.254", Community: "my_comm"}},
 			},
-			wantErr: true,
+			wantErr:   false,
+			wantHosts: 1,
 		},
 		{
 			name: "valid hosts",
@@ -71,10 +74,23 @@ func TestApp_initHosts(t *testing.T) {
 				log:   NewLogger(true, os.Stdout),
 			},
 			args: args{
-				hostnames: []string{"127.0.0.1", "127.0.0.1"},
-				community: "my_comm",
+				hosts: []HostConfig{{Name: agent.Target, Community: "my_comm"}, {Name: agent.Target, Community: "my_comm"}},
 			},
-			wantErr: false,
+			wantErr:   false,
+			wantHosts: 2,
+		},
+		{
+			name: "collector host",
+			fields: fields{
+				hosts: make([]*Host, 0),
+				gui:   &gocui.Gui{},
+				log:   NewLogger(true, os.Stdout),
+			},
+			args: args{
+				hosts: []HostConfig{{Name: "sim1", Collector: NewSimCollector("sim1", 4, 1, NewLogger(true, os.Stdout))}},
+			},
+			wantErr:   false,
+			wantHosts: 1,
 		},
 		{
 			name: "valid and invalid hosts",
@@ -84,22 +100,102 @@ func TestApp_initHosts(t *testing.T) {
 				log:   NewLogger(true, os.Stdout),
 			},
 			args: args{
-				hostnames: []string{"127.0.0.1", "invalid-host"},
-				community: "my_comm",
+				hosts: []HostConfig{{Name: agent.Target, Community: "my_comm"}, {Name: "invalid-host", Community: "my_comm"}},
 			},
-			wantErr: false,
+			wantErr:   false,
+			wantHosts: 2,
 		},
 	}
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
 			a := &App{
 				hosts: tt.fields.hosts,
 				gui:   tt.fields.gui,
 				log:   tt.fields.log,
 			}
-			if err := a.initHosts(tt.args.hostnames, tt.args.community); (err != nil) != tt.wantErr {
+			if err := a.initHosts(tt.args.hosts); (err != nil) != tt.wantErr {
 				t.Errorf("App.initHosts() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if len(a.hosts) != tt.wantHosts {
+				t.Errorf("len(App.hosts) = %v, want %v", len(a.hosts), tt.wantHosts)
+			}
 		})
 	}
 }
+
+func TestApp_addHost_removeHost(t *testing.T) {
+	core := newTestAgent(t, "my_comm", "oids1")
+	lab := newTestAgent(t, "my_comm", "oids1")
+	ctx, cancel := context.WithCancel(context.Background())
+	// Polling goroutines exit without polling, only the host list is tested.
+	cancel()
+	l := NewLogger(true, os.Stdout)
+	a := &App{
+		hosts:     []*Host{newHost(core.Target, "my_comm", l)},
+		gui:       &gocui.Gui{},
+		log:       l,
+		ctx:       ctx,
+		cancels:   make(map[*Host]context.CancelFunc),
+		community: "my_comm",
+	}
+	defer a.pollers.Wait()
+	a.mw = NewMainWidget("main", a.hosts, NewNarrowWidget("regexp", "", l), l)
+	a.mw.Markeds = []ifKey{{core.Target, 4}}
+
+	if err := a.addHost(HostConfig{Name: core.Target}); err == nil {
+		t.Errorf("App.addHost() accepts a duplicated host")
+	}
+	if err := a.addHost(HostConfig{Name: aggregateHostName}); err == nil {
+		t.Errorf("App.addHost() accepts the name of the aggregate host")
+	}
+	if err := a.addHost(ParseAgent("lab/" + lab.Target)); err != nil {
+		t.Errorf("App.addHost() error = %v", err)
+	}
+	if len(a.mw.Hosts) != 2 || a.mw.Hosts[1].collector.(*SNMPCollector).params.Community != "my_comm" || a.mw.Hosts[1].Group != "lab" {
+		t.Errorf("MainWidget.Hosts = %v, want 2 hosts with default community", a.mw.Hosts)
+	}
+	if err := a.removeHost(core.Target); err != nil {
+		t.Errorf("App.removeHost() error = %v", err)
+	}
+	if len(a.mw.Hosts) != 1 || a.mw.Hosts[0].Name != lab.Target {
+		t.Errorf("MainWidget.Hosts = %v, want only %v", a.mw.Hosts, lab.Target)
+	}
+	if len(a.mw.Markeds) != 0 {
+		t.Errorf("MainWidget.Markeds = %v, want no marks", a.mw.Markeds)
+	}
+	if err := a.removeHost(core.Target); err == nil {
+		t.Errorf("App.removeHost() removes an unknown host")
+	}
+}
+
+func TestApp_status(t *testing.T) {
+	l := NewLogger(false, os.Stdout)
+	tests := []struct {
+		name     string
+		deadline time.Time
+		want     string
+	}{
+		{"unlimited", time.Time{}, "polling 0"},
+		{"lifespan", time.Now().Add(time.Hour + 500*time.Millisecond), "polling 0, quit in 1h0m0s"},
+		{"over", time.Now().Add(-time.Second), "polling 0, quit in 0s"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{log: l, deadline: tt.deadline}
+			if got := a.status(); got != tt.want {
+				t.Errorf("App.status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_suicide(t *testing.T) {
+	a := &App{log: NewLogger(false, os.Stdout)}
+	a.suicide(context.Background(), 0)
+	if !a.deadline.IsZero() {
+		t.Errorf("deadline = %v with lifespan 0, want none", a.deadline)
+	}
+}
@@ -18,13 +18,36 @@ var (
 	revision string = "unkown"
 )
 
+// minFast is the shortest -fast interval [sec], so that agents are not
+// flooded with GETs
+const minFast = 0.1
+
 func main() {
-	e := flag.String("e", "", `narrow down to IFs that match with regular expressions.
-	Matching target is IF name and IF Description`)
+	e := flag.String("e", "", `narrow down to IFs that match with a filter expression.
+	e.g. "host=~core.* and in_util>50 and not status=Down".
+	A bare regular expression matches IF name and IF Description`)
 	d := flag.Bool("debug", false, "start with debug mode. deubg mode dump trace log")
 	c := flag.String("c", "public", "snmp community string.")
-	i := flag.Int("i", 10, "SNMP polling interval [sec]. minimum 5")
-	l := flag.Int("l", 7200, "trmon continuous operation time [sec].")
+	i := flag.Int("i", 10, "SNMP polling interval [sec]. minimum 1")
+	fast := flag.Float64("fast", 1, `polling interval [sec] of marked I/Fs and the one in the detail view, which may be below 1.
+	minimum 0.1, 0 polls them with the others`)
+	l := flag.Int("l", 7200, "trmon continuous operation time [sec]. 0 runs until quit.")
+	s := flag.String("s", trmon.DefaultStatePath(), `state file to keep marks, filter, sort, unit and toggles between sessions.
+	empty disables it`)
+	conf := flag.String("f", "", "configuration file (JSON) listing agents with their community and group.")
+	cdp := flag.Bool("cdp", false, "also poll CISCO-CDP-MIB for the Neighbor column. LLDP-MIB is always polled.")
+	dot3 := flag.Bool("dot3", false, "also poll dot3StatsTable of EtherLike-MIB for the error breakdown in the detail view.")
+	optics := flag.Bool("optics", false, "also poll ENTITY-SENSOR-MIB for optical power, temperature and bias in the detail view.")
+	name := flag.String("name", "", `label of the Name column, "target" as given or "sysname". default is the last one used`)
+	ifname := flag.String("ifname", "", `label of the I/F column, "descr" for ifDescr or "name" for ifName. default is the last one used`)
+	simulate := flag.Int("simulate", 0, "add N hosts with fabricated traffic for demos and load tests. no network is used.")
+	simulateIFs := flag.Int("simulate-ifs", 48, "number of interfaces of each simulated host.")
+	maxPolls := flag.Int("max-polls", trmon.DefaultMaxPolls, "number of agents polled at once. 0 means no limit.")
+	timeout := flag.Int("timeout", 3, "SNMP timeout [sec].")
+	retries := flag.Int("retries", 0, "number of times an SNMP request is resent after a timeout.")
+	maxRep := flag.Int("max-repetitions", trmon.DefaultMaxRepetitions, "max-repetitions of GetBulk requests.")
+	nonRep := flag.Int("non-repeaters", 0, "non-repeaters of GetBulk requests.")
+	autotune := flag.Bool("autotune", false, "adjust max-repetitions of each agent to its responses and timeouts.")
 	v := flag.Bool("v", false, "show app version")
 	flag.Parse()
 
@@ -37,12 +60,22 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *i < 5 {
-		log.Println("Too short interval, The minimum SNMP polling interval is 5 seconds")
+	if *i < 1 {
+		log.Println("Too short interval, The minimum SNMP polling interval is 1 second")
 		os.Exit(1)
 	}
 
-	if len(flag.Args()) < 1 {
+	if *fast != 0 && *fast < minFast {
+		log.Printf("Too short fast interval, The minimum is %v second, or 0 to disable it", minFast)
+		os.Exit(1)
+	}
+
+	if *simulate < 0 || *simulateIFs < 1 {
+		log.Println("-simulate must be 0 or more and -simulate-ifs 1 or more")
+		os.Exit(1)
+	}
+
+	if len(flag.Args()) < 1 && *conf == "" && *simulate < 1 {
 		log.Println("Must specify at least one host")
 		os.Exit(1)
 	}
@@ -62,14 +95,33 @@ func main() {
 	}
 
 	config := &trmon.Config{
-		Interval:  *i,
-		Lifespan:  *l,
-		Community: *c,
-		Expr:      *e,
-		IsDebug:   *d,
-		Output:    f,
+		Interval:       *i,
+		FastInterval:   time.Duration(*fast * float64(time.Second)),
+		Lifespan:       *l,
+		Community:      *c,
+		Expr:           *e,
+		IsDebug:        *d,
+		Output:         f,
+		StateFile:      *s,
+		ConfigFile:     *conf,
+		CDP:            *cdp,
+		Dot3:           *dot3,
+		Optics:         *optics,
+		HostLabel:      *name,
+		IFLabel:        *ifname,
+		Simulate:       *simulate,
+		SimulateIFs:    *simulateIFs,
+		MaxPolls:       *maxPolls,
+		Timeout:        *timeout,
+		Retries:        *retries,
+		MaxRepetitions: *maxRep,
+		NonRepeaters:   *nonRep,
+		AutoTune:       *autotune,
 	}
 
 	app := new(trmon.App)
-	app.Run(flag.Args(), config)
+	if err := app.Run(flag.Args(), config); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,19 @@
+package trmon
+
+// Collector reads the interfaces of a host. Its methods are only called
+// from the goroutine polling the host, so it needs no locking of its own.
+// Interfaces are created by NewIF and their counters are fed by
+// Counter.Update. It is given to App by HostConfig.Collector in
+// Config.Hosts.
+type Collector interface {
+	// Discover returns the interfaces of the host by ifIndex.
+	Discover() (map[int]*IF, error)
+	// Collect updates the counters of ifs in place and returns the
+	// interfaces to poll from now on, which may differ from ifs when
+	// interfaces come and go. ifs belong to the polling goroutine, and the
+	// UI reads copies of them taken after each poll.
+	Collect(ifs map[int]*IF) (map[int]*IF, error)
+	// Health returns the state of the device from the last Collect, or
+	// nil when it is unknown.
+	Health() *Health
+}
@@ -0,0 +1,408 @@
+package trmon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// CommandWidget is the vim-like ':' command line.
+type CommandWidget struct {
+	Name     string
+	commands []command
+	history  []string
+	histPos  int
+	log      *Logger
+}
+
+type command struct {
+	name  string
+	usage string
+	// args returns completion candidates of the first argument
+	args func() []string
+	run  func(g *gocui.Gui, args []string) error
+}
+
+func NewCommandWidget(name string, commands []command, l *Logger) *CommandWidget {
+	return &CommandWidget{
+		Name:     name,
+		commands: commands,
+		log:      l,
+	}
+}
+
+func fixed(candidates ...string) func() []string {
+	return func() []string { return candidates }
+}
+
+// commands returns the commands available from the command line.
+func (a *App) commands(mw *MainWidget, nw *NarrowWidget) []command {
+	return []command{
+		{
+			name:  "sort",
+			usage: "sort [column] [asc|desc]",
+			args:  fixed(sortKeys...),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) == 0 {
+					return mw.setSort("", false)
+				}
+				desc := len(args) > 1 && args[1] == "desc"
+				return mw.setSort(args[0], desc)
+			},
+		},
+		{
+			name:  "interval",
+			usage: "interval <sec>",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: interval <sec>")
+				}
+				sec, err := strconv.Atoi(args[0])
+				if err != nil {
+					return err
+				}
+				return a.setInterval(sec)
+			},
+		},
+		{
+			name:  "add",
+			usage: "add [group/]<agent> [community]",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) < 1 || len(args) > 2 {
+					return fmt.Errorf("usage: add [group/]<agent> [community]")
+				}
+				hc := ParseAgent(args[0])
+				if len(args) == 2 {
+					hc.Community = args[1]
+				}
+				return a.addHost(hc)
+			},
+		},
+		{
+			name:  "remove",
+			usage: "remove <agent>",
+			args: func() []string {
+				names := make([]string, 0, len(a.hosts))
+				for _, h := range a.hosts {
+					names = append(names, h.Name)
+				}
+				return names
+			},
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: remove <agent>")
+				}
+				return a.removeHost(args[0])
+			},
+		},
+		{
+			name:  "unit",
+			usage: "unit <bps|kbps|mbps|pps|kpps|mpps>",
+			args:  fixed(Bps.String(), Kbps.String(), Mbps.String(), Pps.String(), Kpps.String(), Mpps.String()),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: unit <bps|kbps|mbps|pps|kpps|mpps>")
+				}
+				u, err := parseUnit(args[0])
+				if err != nil {
+					return err
+				}
+				mw.displaybps = u < Pps
+				return mw.setUnit(u)
+			},
+		},
+		{
+			name:  "export",
+			usage: "export <file.csv>",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: export <file.csv>")
+				}
+				f, err := os.Create(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				return mw.export(f)
+			},
+		},
+		{
+			name:  "filter",
+			usage: "filter [expr]",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				return setFilterView(g, nw, strings.Join(args, " "))
+			},
+		},
+		{
+			name:  "filtermode",
+			usage: "filtermode <highlight|hide>",
+			args:  fixed("highlight", "hide"),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 || (args[0] != "highlight" && args[0] != "hide") {
+					return fmt.Errorf("usage: filtermode <highlight|hide>")
+				}
+				nw.hide = args[0] == "hide"
+				return nil
+			},
+		},
+		{
+			name:  "group",
+			usage: "group <on|off>",
+			args:  fixed("on", "off"),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+					return fmt.Errorf("usage: group <on|off>")
+				}
+				mw.grouped = args[0] == "on"
+				return nil
+			},
+		},
+		{
+			name:  "mark",
+			usage: "mark <all|none>",
+			args:  fixed("all", "none"),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: mark <all|none>")
+				}
+				switch args[0] {
+				case "all":
+					mw.markAll()
+				case "none":
+					mw.Markeds = mw.Markeds[:0]
+				default:
+					return fmt.Errorf("usage: mark <all|none>")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "aggregate",
+			usage: "aggregate <name>",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: aggregate <name>")
+				}
+				return a.aggregateMarked(args[0])
+			},
+		},
+		{
+			name:  "unaggregate",
+			usage: "unaggregate <name>",
+			args: func() []string {
+				names := make([]string, 0, len(mw.aggregates))
+				for _, ag := range mw.aggregates {
+					names = append(names, ag.Name)
+				}
+				return names
+			},
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: unaggregate <name>")
+				}
+				return a.removeAggregate(args[0])
+			},
+		},
+		{
+			name:  "breakdown",
+			usage: "breakdown <on|off>",
+			args:  fixed("on", "off"),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+					return fmt.Errorf("usage: breakdown <on|off>")
+				}
+				mw.breakdown = args[0] == "on"
+				return nil
+			},
+		},
+		{
+			name:  "hostname",
+			usage: "hostname <target|sysname>",
+			args:  fixed(hostLabelTarget, hostLabelSysName),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: hostname <target|sysname>")
+				}
+				return mw.setHostLabel(args[0])
+			},
+		},
+		{
+			name:  "ifname",
+			usage: "ifname <descr|name>",
+			args:  fixed(ifLabelDescr, ifLabelName),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("usage: ifname <descr|name>")
+				}
+				return mw.setIFLabel(args[0])
+			},
+		},
+		{
+			name:  "alert",
+			usage: "alert <expr>",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				if len(args) == 0 {
+					return fmt.Errorf("usage: alert <expr>")
+				}
+				return mw.addAlert(strings.Join(args, " "), false)
+			},
+		},
+		{
+			name:  "unalert",
+			usage: "unalert [expr]",
+			args:  mw.alertExprs,
+			run: func(g *gocui.Gui, args []string) error {
+				return mw.removeAlert(strings.Join(args, " "))
+			},
+		},
+		{
+			name:  "reload",
+			usage: "reload",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				return a.reload(g)
+			},
+		},
+		{
+			name:  "help",
+			usage: "help",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				return createHelp(g, nil)
+			},
+		},
+		{
+			name:  "quit",
+			usage: "quit",
+			args:  fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				return gocui.ErrQuit
+			},
+		},
+	}
+}
+
+func (c *CommandWidget) lookup(name string) (command, bool) {
+	for _, cmd := range c.commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// exec runs a command line and records it in the history.
+func (c *CommandWidget) exec(g *gocui.Gui, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	if len(c.history) == 0 || c.history[len(c.history)-1] != line {
+		c.history = append(c.history, line)
+	}
+	c.histPos = len(c.history)
+
+	cmd, ok := c.lookup(fields[0])
+	if !ok {
+		return fmt.Errorf("Unknown command %v", fields[0])
+	}
+	c.log.Debug().Msgf("exec command %v", line)
+	return cmd.run(g, fields[1:])
+}
+
+// complete completes the command name or its first argument.
+// Candidates are shown in the title when there is more than one.
+func (c *CommandWidget) complete(v *gocui.View) {
+	line, _ := v.Line(0)
+	fields := strings.Fields(line)
+	if line == "" || strings.HasSuffix(line, " ") {
+		fields = append(fields, "")
+	}
+
+	var candidates []string
+	switch len(fields) {
+	case 1:
+		for _, cmd := range c.commands {
+			candidates = append(candidates, cmd.name)
+		}
+	case 2:
+		cmd, ok := c.lookup(fields[0])
+		if !ok {
+			return
+		}
+		candidates = cmd.args()
+	default:
+		return
+	}
+
+	last := fields[len(fields)-1]
+	matches := make([]string, 0)
+	for _, s := range candidates {
+		if strings.HasPrefix(s, last) {
+			matches = append(matches, s)
+		}
+	}
+	if len(matches) == 0 {
+		return
+	}
+	completed := commonPrefix(matches)
+	if len(matches) == 1 {
+		completed += " "
+	} else {
+		v.Title = strings.Join(matches, " ")
+	}
+	fields[len(fields)-1] = completed
+	setLine(v, strings.Join(fields, " "))
+}
+
+// recall replaces the line with the history entry d steps away.
+func (c *CommandWidget) recall(v *gocui.View, d int) {
+	pos := c.histPos + d
+	if pos < 0 || pos > len(c.history) {
+		return
+	}
+	c.histPos = pos
+	if pos == len(c.history) {
+		setLine(v, "")
+		return
+	}
+	setLine(v, c.history[pos])
+}
+
+func (c *CommandWidget) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch key {
+	case gocui.KeyTab:
+		c.complete(v)
+	case gocui.KeyArrowUp:
+		c.recall(v, -1)
+	case gocui.KeyArrowDown:
+		c.recall(v, 1)
+	default:
+		(&Editor{}).Edit(v, key, ch, mod)
+	}
+}
+
+func setLine(v *gocui.View, line string) {
+	v.Clear()
+	fmt.Fprint(v, line)
+	v.SetCursor(len(line), 0)
+}
+
+func commonPrefix(list []string) string {
+	prefix := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
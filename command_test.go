@@ -0,0 +1,92 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestCommandWidget_exec(t *testing.T) {
+	var got []string
+	c := NewCommandWidget("cmdline", []command{
+		{
+			name: "sort",
+			args: fixed(),
+			run: func(g *gocui.Gui, args []string) error {
+				got = args
+				return nil
+			},
+		},
+	}, NewLogger(true, os.Stdout))
+
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty line",
+			line: "  ",
+			want: nil,
+		},
+		{
+			name: "command with args",
+			line: "sort  in desc",
+			want: []string{"in", "desc"},
+		},
+		{
+			name:    "unknown command",
+			line:    "hoge",
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if err := c.exec(nil, tt.line); (err != nil) != tt.wantErr {
+				t.Errorf("CommandWidget.exec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommandWidget.exec() args = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if want := []string{"sort  in desc", "hoge"}; !reflect.DeepEqual(c.history, want) {
+		t.Errorf("CommandWidget.history = %v, want %v", c.history, want)
+	}
+}
+
+func Test_commonPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		list []string
+		want string
+	}{
+		{
+			name: "single",
+			list: []string{"interval"},
+			want: "interval",
+		},
+		{
+			name: "shared prefix",
+			list: []string{"inerr", "indis", "in"},
+			want: "in",
+		},
+		{
+			name: "no shared prefix",
+			list: []string{"out", "in"},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commonPrefix(tt.list); got != tt.want {
+				t.Errorf("commonPrefix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
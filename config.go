@@ -0,0 +1,189 @@
+package trmon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileConfig is the configuration file given by -f.
+//
+//	{
+//	  "hosts": [
+//	    {"name": "core1", "community": "my_comm", "group": "tokyo", "cdp": true}
+//	  ],
+//	  "aggregates": [
+//	    {"name": "tokyo-uplinks", "members": [{"host": "core1", "if": "ge-0/0/1"}]}
+//	  ],
+//	  "alerts": ["in_bcast>1k"],
+//	  "interval": 30,
+//	  "filter": "in_util>50",
+//	  "dom_profiles": [
+//	    {"name": "juniper", "rx_power": {"oid": ".1.3.6.1.4.1.2636.3.60.1.1.1.1.5", "scale": 0.01}}
+//	  ],
+//	  "optic_thresholds": {"rx_low": -14, "tx_low": -8, "temp_high": 70},
+//	  "health_profiles": [
+//	    {"name": "huawei", "cpu": ".1.3.6.1.4.1.2011.5.25.31.1.1.1.1.5", "memory": ".1.3.6.1.4.1.2011.5.25.31.1.1.1.1.7"}
+//	  ]
+//	}
+type FileConfig struct {
+	Hosts      []HostConfig      `json:"hosts"`
+	Aggregates []AggregateConfig `json:"aggregates"`
+	// Alerts are filter expressions showing matching interfaces in red
+	Alerts      []string     `json:"alerts"`
+	DOMProfiles []DOMProfile `json:"dom_profiles"`
+	// OpticThresholds replaces DefaultOpticThresholds
+	OpticThresholds *OpticThresholds `json:"optic_thresholds"`
+	// HealthProfiles are added to, or replace, the builtin ones by name
+	HealthProfiles []HealthProfile `json:"health_profiles"`
+	// Interval replaces -i when given, and Filter is used unless -e is
+	// given instead of the one of the state file
+	Interval int    `json:"interval"`
+	Filter   string `json:"filter"`
+}
+
+func (fc *FileConfig) opticThresholds() OpticThresholds {
+	if fc.OpticThresholds == nil {
+		return DefaultOpticThresholds
+	}
+	return *fc.OpticThresholds
+}
+
+func (fc *FileConfig) domProfile(name string) *DOMProfile {
+	for i := range fc.DOMProfiles {
+		if fc.DOMProfiles[i].Name == name {
+			return &fc.DOMProfiles[i]
+		}
+	}
+	return nil
+}
+
+func (fc *FileConfig) healthProfile(name string) *HealthProfile {
+	for i := range fc.HealthProfiles {
+		if fc.HealthProfiles[i].Name == name {
+			return &fc.HealthProfiles[i]
+		}
+	}
+	return nil
+}
+
+// HostConfig is an agent to monitor. An empty Community means the default
+// one of Config.
+type HostConfig struct {
+	Name      string `json:"name"`
+	Community string `json:"community"`
+	Group     string `json:"group"`
+	// CDP walks CISCO-CDP-MIB for neighbors in addition to LLDP-MIB
+	CDP bool `json:"cdp"`
+	// Dot3 walks dot3StatsTable of EtherLike-MIB for the error breakdown
+	Dot3 bool `json:"dot3"`
+	// Optics walks ENTITY-SENSOR-MIB for transceiver DOM readings
+	Optics bool `json:"optics"`
+	// DOMProfile names a profile of FileConfig.DOMProfiles to walk
+	// instead of ENTITY-SENSOR-MIB
+	DOMProfile string `json:"dom_profile"`
+	// HealthProfile names a health profile to walk instead of
+	// HOST-RESOURCES-MIB
+	HealthProfile string `json:"health_profile"`
+	// Timeout is the SNMP timeout in seconds and Retries is how many
+	// times a request is resent after a timeout. MaxRepetitions and
+	// NonRepeaters are of GetBulk. Zero values take the global options,
+	// and so does a missing Retries, so that 0 turns retries off.
+	Timeout        int  `json:"timeout"`
+	Retries        *int `json:"retries"`
+	MaxRepetitions int  `json:"max_repetitions"`
+	NonRepeaters   int  `json:"non_repeaters"`
+	AutoTune       bool `json:"autotune"`
+	// Collector reads the host instead of SNMP when given, for programs
+	// embedding trmon with another backend. The options above are then
+	// ignored.
+	Collector Collector `json:"-"`
+}
+
+func (hc HostConfig) snmpOptions() SNMPOptions {
+	return SNMPOptions{
+		Timeout:        time.Duration(hc.Timeout) * time.Second,
+		Retries:        hc.Retries,
+		MaxRepetitions: hc.MaxRepetitions,
+		NonRepeaters:   hc.NonRepeaters,
+		AutoTune:       hc.AutoTune,
+	}
+}
+
+func LoadConfigFile(path string) (*FileConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fc := new(FileConfig)
+	if err := json.Unmarshal(b, fc); err != nil {
+		return nil, err
+	}
+	if err := fc.validate(); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// validate checks the settings applied as a whole, so that an invalid
+// file is rejected before anything of it is applied.
+func (fc *FileConfig) validate() error {
+	if fc.Interval < 0 {
+		return fmt.Errorf("interval %v is below 1 second", fc.Interval)
+	}
+	if _, err := parseFilter(fc.Filter); err != nil {
+		return fmt.Errorf("filter %q: %w", fc.Filter, err)
+	}
+	hosts := make(map[string]bool, len(fc.Hosts))
+	for _, hc := range fc.Hosts {
+		switch {
+		case hc.Name == "":
+			return fmt.Errorf("host needs a name")
+		case hc.Name == aggregateHostName:
+			return fmt.Errorf("host %v is the name of the aggregate host", hc.Name)
+		case hosts[hc.Name]:
+			return fmt.Errorf("host %v is listed twice", hc.Name)
+		}
+		hosts[hc.Name] = true
+	}
+	aggregates := make(map[string]bool, len(fc.Aggregates))
+	for _, ac := range fc.Aggregates {
+		switch {
+		case ac.Name == "" || len(ac.Members) == 0:
+			return fmt.Errorf("aggregate %q needs a name and members", ac.Name)
+		case aggregates[ac.Name]:
+			return fmt.Errorf("aggregate %v is listed twice", ac.Name)
+		}
+		aggregates[ac.Name] = true
+		for _, m := range ac.Members {
+			if m.Host == "" || (m.IF == "" && m.Index == 0) {
+				return fmt.Errorf("aggregate %v: member needs a host and an if or index", ac.Name)
+			}
+		}
+	}
+	alerts := make(map[string]bool, len(fc.Alerts))
+	for _, expr := range fc.Alerts {
+		switch {
+		case expr == "":
+			return fmt.Errorf("alert needs an expression")
+		case alerts[expr]:
+			return fmt.Errorf("alert %v is listed twice", expr)
+		}
+		alerts[expr] = true
+		if _, err := parseFilter(expr); err != nil {
+			return fmt.Errorf("alert %q: %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// ParseAgent parses an agent given on the command line. The agent may be
+// labeled with a group as "<group>/<agent>".
+func ParseAgent(s string) HostConfig {
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		return HostConfig{Name: s[i+1:], Group: s[:i]}
+	}
+	return HostConfig{Name: s}
+}
@@ -0,0 +1,92 @@
+package trmon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAgent(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent string
+		want  HostConfig
+	}{
+		{
+			name:  "agent only",
+			agent: "192.0.2.1",
+			want:  HostConfig{Name: "192.0.2.1"},
+		},
+		{
+			name:  "group label",
+			agent: "tokyo/core1",
+			want:  HostConfig{Name: "core1", Group: "tokyo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAgent(tt.agent); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAgent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trmon.json")
+	os.WriteFile(path, []byte(`{"hosts": [{"name": "core1", "community": "my_comm", "group": "tokyo", "timeout": 10, "retries": 2}]}`), 0644)
+
+	fc, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	retries := 2
+	want := []HostConfig{{Name: "core1", Community: "my_comm", Group: "tokyo", Timeout: 10, Retries: &retries}}
+	if !reflect.DeepEqual(fc.Hosts, want) {
+		t.Errorf("FileConfig.Hosts = %v, want %v", fc.Hosts, want)
+	}
+
+	if _, err := LoadConfigFile(filepath.Join(t.TempDir(), "none.json")); err == nil {
+		t.Errorf("LoadConfigFile() of a missing file error = nil")
+	}
+}
+
+func TestFileConfig_validate(t *testing.T) {
+	member := []MemberConfig{{Host: "core1", IF: "ge-0/0/1"}}
+	tests := []struct {
+		name    string
+		fc      FileConfig
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			fc: FileConfig{
+				Hosts:      []HostConfig{{Name: "core1"}, {Name: "core2"}},
+				Aggregates: []AggregateConfig{{Name: "uplinks", Members: member}},
+				Alerts:     []string{"in_bcast>1k", "in_err>0"},
+				Interval:   30,
+				Filter:     "host=~core.*",
+			},
+		},
+		{"negative interval", FileConfig{Interval: -1}, true},
+		{"invalid filter", FileConfig{Filter: "(status=UP"}, true},
+		{"duplicate host", FileConfig{Hosts: []HostConfig{{Name: "core1"}, {Name: "core1"}}}, true},
+		{"aggregate host", FileConfig{Hosts: []HostConfig{{Name: aggregateHostName}}}, true},
+		{"unnamed aggregate", FileConfig{Aggregates: []AggregateConfig{{Members: member}}}, true},
+		{"aggregate without members", FileConfig{Aggregates: []AggregateConfig{{Name: "uplinks"}}}, true},
+		{"duplicate aggregate", FileConfig{Aggregates: []AggregateConfig{{Name: "uplinks", Members: member}, {Name: "uplinks", Members: member}}}, true},
+		{"member without if", FileConfig{Aggregates: []AggregateConfig{{Name: "uplinks", Members: []MemberConfig{{Host: "core1"}}}}}, true},
+		{"invalid alert", FileConfig{Alerts: []string{"in>fast"}}, true},
+		{"empty alert", FileConfig{Alerts: []string{""}}, true},
+		{"duplicate alert", FileConfig{Alerts: []string{"in_err>0", "in_err>0"}}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fc.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("FileConfig.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package trmon
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/jroimartin/gocui"
+)
+
+const detailView = "detail"
+
+// layoutDetail refreshes the detail view while an interface is selected.
+func (m *MainWidget) layoutDetail(g *gocui.Gui) error {
+	if m.detail == nil {
+		return nil
+	}
+	r, ok := m.lookup(*m.detail)
+	if !ok {
+		// The host was removed or the interface disappeared
+		m.detail = nil
+		g.DeleteView(detailView)
+		if _, err := g.SetCurrentView(m.Name); err != nil {
+			return err
+		}
+		return nil
+	}
+	maxX, maxY := g.Size()
+	v, err := g.SetView(detailView, maxX/10, maxY/6, maxX*9/10, maxY*5/6)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+	}
+	v.Title = fmt.Sprintf("%v %v", m.hostName(r.host), m.ifName(r.ifc))
+	v.Clear()
+	m.printDetail(v, r)
+	return nil
+}
+
+// printDetail writes every value collected for the interface.
+func (m *MainWidget) printDetail(v *gocui.View, r ifRow) {
+	fmt.Fprintf(v, "%-12s %v\n", "Host", r.host.Name)
+	if hl := r.host.Health; hl != nil {
+		fmt.Fprintf(v, "%-12s %v\n", "sysName", hl.SysName)
+		fmt.Fprintf(v, "%-12s %v\n", "sysDescr", hl.SysDescr)
+		fmt.Fprintf(v, "%-12s %v\n", "Health", hl.summary())
+	}
+	if s, ok := r.host.collector.(sessionReporter); ok {
+		fmt.Fprintf(v, "%-12s %v\n", "Session", s.session())
+	}
+	if !r.host.PolledAt.IsZero() {
+		fmt.Fprintf(v, "%-12s %v at %v\n", "Poll", r.host.PollTime.Round(time.Millisecond), r.host.PolledAt.Format("15:04:05"))
+	}
+	fmt.Fprintf(v, "%-12s %v\n", "ifIndex", r.ifc.Index)
+	fmt.Fprintf(v, "%-12s %v\n", "ifDescr", r.ifc.Desc)
+	fmt.Fprintf(v, "%-12s %v\n", "ifName", r.ifc.Name)
+	fmt.Fprintf(v, "%-12s %v\n", "Description", r.ifc.Alias)
+	fmt.Fprintf(v, "%-12s %v bps\n", "Speed", humanize.Comma(r.ifc.Speed))
+	fmt.Fprintf(v, "%-12s admin %v / oper %v\n", "Status", r.ifc.AdminStatus, r.ifc.OperStatus)
+	if agg, ok := r.host.IFs[r.ifc.LagIndex]; ok {
+		fmt.Fprintf(v, "%-12s member of %v\n", "LAG", m.ifName(agg))
+	}
+	for _, expr := range m.alerting(r) {
+		fmt.Fprintf(v, "%-12s %v\n", "Alert", expr)
+	}
+	for _, n := range r.ifc.Neighbors {
+		fmt.Fprintf(v, "%-12s %v (%v)\n", "Neighbor", n, n.Protocol)
+	}
+	fmt.Fprintln(v)
+
+	fmt.Fprintf(v, "%-14s %24s %16s %14s\n", "Counter", "Last", "Diff", "Rate[/s]")
+	for _, c := range r.ifc.counters() {
+		fmt.Fprintf(v, "%-14s %24s %16s %14s\n", c.name, humanize.Comma(c.Last), humanize.Comma(c.Diff), humanize.Comma(c.Rate))
+	}
+
+	if o := r.ifc.Optics; o != nil {
+		t := m.thresholds
+		fmt.Fprintln(v)
+		fmt.Fprintf(v, "%-12s %v\n", "Rx power", highlight(reading(o.RxPower, "dBm"), t.lowRx(o)))
+		fmt.Fprintf(v, "%-12s %v\n", "Tx power", highlight(reading(o.TxPower, "dBm"), t.lowTx(o)))
+		fmt.Fprintf(v, "%-12s %v\n", "Temperature", highlight(reading(o.Temperature, "C"), t.hot(o)))
+		fmt.Fprintf(v, "%-12s %v\n", "Bias", reading(o.Bias, "mA"))
+	}
+
+	if d := r.ifc.Dot3; d != nil {
+		fmt.Fprintln(v)
+		fmt.Fprintf(v, "%-20s %18s %16s\n", "Ethernet error", "Last", "Diff")
+		for _, c := range d.counters() {
+			fmt.Fprintf(v, "%-20s %18s %16s\n", c.name, humanize.Comma(c.Last), humanize.Comma(c.Diff))
+		}
+		if s := d.diagnosis(); s != "" {
+			fmt.Fprintln(v, s)
+		}
+	}
+
+	if members := r.host.lagMembers(r.ifc.Index); !r.host.virtual && len(members) > 0 {
+		fmt.Fprintln(v)
+		if r.host.lagImbalanced(r.ifc.Index) {
+			fmt.Fprintln(v, "LAG members are imbalanced")
+		}
+		fmt.Fprintf(v, "%-20s %6s %16s %16s\n", "LAG member", "Stat", fmt.Sprintf("IN[%v]", m.unit), fmt.Sprintf("OUT[%v]", m.unit))
+		for _, i := range members {
+			in, out := m.rates(i)
+			fmt.Fprintf(v, "%-20s %6s %16s %16s\n", m.ifName(i), i.OperStatus, humanize.Comma(in), humanize.Comma(out))
+		}
+	}
+
+	if ag := m.aggregateOf(r); ag != nil {
+		fmt.Fprintln(v)
+		fmt.Fprintf(v, "%-20s %-20s %6s %16s %16s\n", "Member", "I/F", "Stat", fmt.Sprintf("IN[%v]", m.unit), fmt.Sprintf("OUT[%v]", m.unit))
+		for _, h := range m.Hosts {
+			for _, i := range h.IFs {
+				for _, mc := range ag.Members {
+					if h.virtual || !mc.match(h, i) {
+						continue
+					}
+					in, out := m.rates(i)
+					fmt.Fprintf(v, "%-20s %-20s %6s %16s %16s\n", m.hostName(h), m.ifName(i), i.OperStatus, humanize.Comma(in), humanize.Comma(out))
+				}
+			}
+		}
+	}
+}
+
+func reading(v float64, unit string) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f %v", v, unit)
+}
+
+// highlight colors s in red when on.
+func highlight(s string, on bool) string {
+	if !on {
+		return s
+	}
+	return "\x1b[31m" + s + "\x1b[0m"
+}
+
+// aggregateOf returns the aggregate shown by the row, if any.
+func (m *MainWidget) aggregateOf(r ifRow) *Aggregate {
+	if !r.host.virtual {
+		return nil
+	}
+	for _, ag := range m.aggregates {
+		if ag.ifc == r.ifc {
+			return ag
+		}
+	}
+	return nil
+}
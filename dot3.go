@@ -0,0 +1,119 @@
+package trmon
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// EtherLike-MIB dot3StatsTable, indexed by dot3StatsIndex which is ifIndex
+	dot3StatsEntry               string = ".1.3.6.1.2.1.10.7.2.1"
+	dot3StatsAlignmentErrors     string = ".1.3.6.1.2.1.10.7.2.1.2."
+	dot3StatsFCSErrors           string = ".1.3.6.1.2.1.10.7.2.1.3."
+	dot3StatsLateCollisions      string = ".1.3.6.1.2.1.10.7.2.1.8."
+	dot3StatsExcessiveCollisions string = ".1.3.6.1.2.1.10.7.2.1.9."
+	dot3StatsFrameTooLongs       string = ".1.3.6.1.2.1.10.7.2.1.13."
+	dot3StatsSymbolErrors        string = ".1.3.6.1.2.1.10.7.2.1.18."
+)
+
+// Dot3Stats is the error breakdown of an Ethernet interface.
+type Dot3Stats struct {
+	FCSErrors           *Counter
+	AlignmentErrors     *Counter
+	LateCollisions      *Counter
+	ExcessiveCollisions *Counter
+	FrameTooLongs       *Counter
+	SymbolErrors        *Counter
+}
+
+func newDot3Stats(l *Logger) *Dot3Stats {
+	return &Dot3Stats{
+		FCSErrors:           newCounter("FCSErrors", l),
+		AlignmentErrors:     newCounter("AlignmentErrors", l),
+		LateCollisions:      newCounter("LateCollisions", l),
+		ExcessiveCollisions: newCounter("ExcessiveCollisions", l),
+		FrameTooLongs:       newCounter("FrameTooLongs", l),
+		SymbolErrors:        newCounter("SymbolErrors", l),
+	}
+}
+
+func (d *Dot3Stats) clone() *Dot3Stats {
+	return &Dot3Stats{
+		FCSErrors:           d.FCSErrors.clone(),
+		AlignmentErrors:     d.AlignmentErrors.clone(),
+		LateCollisions:      d.LateCollisions.clone(),
+		ExcessiveCollisions: d.ExcessiveCollisions.clone(),
+		FrameTooLongs:       d.FrameTooLongs.clone(),
+		SymbolErrors:        d.SymbolErrors.clone(),
+	}
+}
+
+func (d *Dot3Stats) counters() []*Counter {
+	return []*Counter{
+		d.FCSErrors,
+		d.AlignmentErrors,
+		d.LateCollisions,
+		d.ExcessiveCollisions,
+		d.FrameTooLongs,
+		d.SymbolErrors,
+	}
+}
+
+// diagnosis tells the likely cause of the errors of the last poll.
+func (d *Dot3Stats) diagnosis() string {
+	switch {
+	case grew(d.LateCollisions):
+		return "late collisions, check for a duplex mismatch"
+	case grew(d.SymbolErrors) || grew(d.FCSErrors) || grew(d.AlignmentErrors):
+		return "corrupted frames, check the optic, cable or connector"
+	case grew(d.ExcessiveCollisions):
+		return "excessive collisions, the half duplex segment is congested"
+	case grew(d.FrameTooLongs):
+		return "frames too long, check the MTU of both ends"
+	}
+	return ""
+}
+
+// grew reports whether c increased since the previous poll. The Diff of
+// the first sample is the lifetime count and tells nothing of now.
+func grew(c *Counter) bool {
+	return !c.BeforeTime.IsZero() && c.Diff > 0
+}
+
+// updateDot3Value sets a dot3StatsTable counter. The breakdown of an
+// interface is created when the agent first reports it.
+func (c *SNMPCollector) updateDot3Value(pdu gosnmp.SnmpPDU) error {
+	s := strings.Split(pdu.Name, ".")
+	index, _ := strconv.Atoi(s[len(s)-1])
+	i, ok := c.ifs[index]
+	if !ok {
+		return nil
+	}
+	var counter *Counter
+	d := i.Dot3
+	if d == nil {
+		d = newDot3Stats(c.log)
+	}
+	switch {
+	case strings.HasPrefix(pdu.Name, dot3StatsAlignmentErrors):
+		counter = d.AlignmentErrors
+	case strings.HasPrefix(pdu.Name, dot3StatsFCSErrors):
+		counter = d.FCSErrors
+	case strings.HasPrefix(pdu.Name, dot3StatsLateCollisions):
+		counter = d.LateCollisions
+	case strings.HasPrefix(pdu.Name, dot3StatsExcessiveCollisions):
+		counter = d.ExcessiveCollisions
+	case strings.HasPrefix(pdu.Name, dot3StatsFrameTooLongs):
+		counter = d.FrameTooLongs
+	case strings.HasPrefix(pdu.Name, dot3StatsSymbolErrors):
+		counter = d.SymbolErrors
+	default:
+		return nil
+	}
+	counter.Update(gosnmp.ToBigInt(pdu.Value).Int64(), time.Now())
+	i.Dot3 = d
+	return nil
+}
@@ -0,0 +1,95 @@
+package trmon
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestSNMPCollector_updateDot3Value(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := testHost("core1", l)
+	c := &SNMPCollector{ifs: h.IFs, log: l}
+	pdus := []gosnmp.SnmpPDU{
+		{Name: dot3StatsFCSErrors + "1", Type: gosnmp.Counter32, Value: uint(10)},
+		{Name: dot3StatsLateCollisions + "1", Type: gosnmp.Counter32, Value: uint(3)},
+		{Name: dot3StatsSymbolErrors + "2", Type: gosnmp.Counter32, Value: uint(7)},
+		// Columns not broken down and unknown interfaces are ignored
+		{Name: ".1.3.6.1.2.1.10.7.2.1.4.3", Type: gosnmp.Counter32, Value: uint(1)},
+		{Name: dot3StatsFCSErrors + "9", Type: gosnmp.Counter32, Value: uint(1)},
+	}
+	for _, pdu := range pdus {
+		if err := c.updateDot3Value(pdu); err != nil {
+			t.Fatalf("SNMPCollector.updateDot3Value() error = %v", err)
+		}
+	}
+	if d := h.IFs[1].Dot3; d == nil || d.FCSErrors.Last != 10 || d.LateCollisions.Last != 3 {
+		t.Errorf("IFs[1].Dot3 = %+v, want FCSErrors 10 and LateCollisions 3", d)
+	}
+	if d := h.IFs[2].Dot3; d == nil || d.SymbolErrors.Last != 7 {
+		t.Errorf("IFs[2].Dot3 = %+v, want SymbolErrors 7", d)
+	}
+	if h.IFs[3].Dot3 != nil {
+		t.Errorf("IFs[3].Dot3 = %+v, want nil", h.IFs[3].Dot3)
+	}
+}
+
+func TestDot3Stats_diagnosis(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	t0 := time.Now()
+	// grow takes two samples of c n apart
+	grow := func(c *Counter, n int64) {
+		c.Update(100, t0)
+		c.Update(100+n, t0.Add(10*time.Second))
+	}
+	tests := []struct {
+		name   string
+		modify func(d *Dot3Stats)
+		want   string
+	}{
+		{
+			name:   "no error",
+			modify: func(d *Dot3Stats) {},
+			want:   "",
+		},
+		{
+			name:   "fcs errors",
+			modify: func(d *Dot3Stats) { grow(d.FCSErrors, 5) },
+			want:   "corrupted frames, check the optic, cable or connector",
+		},
+		{
+			name:   "errors before the first poll",
+			modify: func(d *Dot3Stats) { d.LateCollisions.Update(7, t0) },
+			want:   "",
+		},
+		{
+			name:   "no new error",
+			modify: func(d *Dot3Stats) { grow(d.FCSErrors, 0) },
+			want:   "",
+		},
+		{
+			name: "late collisions win over fcs errors",
+			modify: func(d *Dot3Stats) {
+				grow(d.FCSErrors, 5)
+				grow(d.LateCollisions, 1)
+			},
+			want: "late collisions, check for a duplex mismatch",
+		},
+		{
+			name:   "frame too long",
+			modify: func(d *Dot3Stats) { grow(d.FrameTooLongs, 1) },
+			want:   "frames too long, check the MTU of both ends",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDot3Stats(l)
+			tt.modify(d)
+			if got := d.diagnosis(); got != tt.want {
+				t.Errorf("Dot3Stats.diagnosis() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
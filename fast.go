@@ -0,0 +1,114 @@
+package trmon
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// fastColumns are read by the fast tier, each followed by the ifIndex.
+// They are the status and the octet and packet rates. Errors and discards
+// are shown as the difference between full polls, which a fast read in
+// between would shrink.
+var fastColumns = []string{
+	ifOperStatus + ".",
+	ifHCInOctets,
+	ifHCOutOctets,
+	ifHCInUcastPkts,
+	ifHCOutUcastPkts,
+	ifHCInMulticastPkts,
+	ifHCInBroadcastPkts,
+	ifHCOutMulticastPkts,
+	ifHCOutBroadcastPkts,
+}
+
+// FastCollector is implemented by collectors able to read the counters of
+// a few interfaces without reading the whole table, for the fast tier.
+type FastCollector interface {
+	CollectIFs(ifs map[int]*IF, indexes []int) error
+}
+
+// CollectIFs gets the counters of the interfaces of indexes. A failed GET
+// counts as a failed poll and drops the session, like a failed core walk.
+func (c *SNMPCollector) CollectIFs(ifs map[int]*IF, indexes []int) error {
+	if err := c.connect(); err != nil {
+		return err
+	}
+	c.ifs = ifs
+	c.stats = walkStats{}
+	defer c.endPoll()
+	oids := make([]string, 0, len(indexes)*len(fastColumns))
+	for _, index := range indexes {
+		for _, column := range fastColumns {
+			// the columns lack the leading ".1" to be matched anywhere
+			oids = append(oids, ".1"+column+strconv.Itoa(index))
+		}
+	}
+	for len(oids) > 0 {
+		n := len(oids)
+		if n > gosnmp.MaxOids {
+			n = gosnmp.MaxOids
+		}
+		result, err := c.params.Get(oids[:n])
+		if err != nil {
+			c.stats.broken = err
+			return err
+		}
+		for _, pdu := range result.Variables {
+			switch pdu.Type {
+			case gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+				continue
+			}
+			c.updateIFValue(pdu)
+		}
+		oids = oids[n:]
+	}
+	return nil
+}
+
+// updateFast reads the interfaces of indexes when the collector can do it
+// without a full poll.
+func (h *Host) updateFast(indexes []int) {
+	fc, ok := h.collector.(FastCollector)
+	if !ok || len(indexes) == 0 {
+		return
+	}
+	if err := fc.CollectIFs(h.ifs, indexes); err != nil {
+		h.log.Debug().Msgf("Failed to fast Update %v: %v", h.Name, err)
+	}
+	h.publish()
+}
+
+// fastTargets are the interfaces of the fast tier by host. They are set by
+// the UI and read by the polling goroutines.
+type fastTargets struct {
+	mu      sync.Mutex
+	indexes map[string][]int
+}
+
+func (f *fastTargets) set(keys []ifKey) {
+	indexes := make(map[string][]int)
+	for _, k := range keys {
+		indexes[k.Host] = append(indexes[k.Host], k.Index)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.indexes = indexes
+}
+
+func (f *fastTargets) of(host string) []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.indexes[host]
+}
+
+// fastKeys are the marked interfaces and the one of the detail view.
+func (m *MainWidget) fastKeys() []ifKey {
+	keys := make([]ifKey, 0, len(m.Markeds)+1)
+	keys = append(keys, m.Markeds...)
+	if m.detail != nil && !m.isMarked(*m.detail) {
+		keys = append(keys, *m.detail)
+	}
+	return keys
+}
@@ -0,0 +1,75 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestSNMPCollector_CollectIFs(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
+	h, err := NewHost(agent.Target, "my_comm", NewLogger(true, os.Stdout))
+	if err != nil {
+		t.Fatalf("NewHost() error = %v", err)
+	}
+	defer h.close()
+	h.Update()
+	h.apply()
+	before := h.IFs[5].InOctets.Last
+
+	agent.set(".1.3.6.1.2.1.31.1.1.1.6.4", gosnmp.Counter64, uint64(1611884919191+1500))
+	agent.set(".1.3.6.1.2.1.2.2.1.8.4", gosnmp.Integer, 2)
+	agent.set(".1.3.6.1.2.1.31.1.1.1.6.5", gosnmp.Counter64, uint64(before+700))
+	h.updateFast([]int{4, 99})
+	h.apply()
+	eth0, eth1 := h.IFs[4], h.IFs[5]
+	if eth0.InOctets.Diff != 1500 || eth0.OperStatus != "Down" {
+		t.Errorf("IFs[4] InOctets.Diff = %v, OperStatus = %v, want 1500 and Down", eth0.InOctets.Diff, eth0.OperStatus)
+	}
+	if eth1.InOctets.Last != before {
+		t.Errorf("IFs[5] not in the fast tier is updated")
+	}
+}
+
+func TestSNMPCollector_CollectIFs_failure(t *testing.T) {
+	// nothing listens on the port of a closed agent
+	agent := newTestAgent(t, "my_comm", "oids1")
+	agent.conn.Close()
+	c := NewSNMPCollector(agent.Target, "my_comm", NewLogger(false, os.Stdout))
+	c.params.Timeout = 100 * time.Millisecond
+	defer c.Close()
+
+	if err := c.CollectIFs(map[int]*IF{}, []int{4}); err == nil {
+		t.Fatalf("SNMPCollector.CollectIFs() error = nil")
+	}
+	if c.failedPolls != 1 || c.lastErr == nil || c.params.Conn != nil {
+		t.Errorf("failedPolls = %v, lastErr = %v, connected = %v, want a failed poll and no session", c.failedPolls, c.lastErr, c.params.Conn != nil)
+	}
+	if got := c.session(); !strings.HasPrefix(got, "1 failed polls") {
+		t.Errorf("SNMPCollector.session() = %q, want the failed poll", got)
+	}
+}
+
+func TestMainWidget_fastKeys(t *testing.T) {
+	l := NewLogger(false, os.Stdout)
+	m := NewMainWidget("main", []*Host{testHost("core1", l)}, NewNarrowWidget("filter", "", l), l)
+	m.Markeds = []ifKey{{"core1", 1}, {"core1", 3}}
+	m.detail = &ifKey{"core1", 2}
+	m.fast.set(m.fastKeys())
+	if got := m.fast.of("core1"); !reflect.DeepEqual(got, []int{1, 3, 2}) {
+		t.Errorf("fastTargets.of() = %v, want [1 3 2]", got)
+	}
+
+	m.detail = &ifKey{"core1", 3}
+	m.fast.set(m.fastKeys())
+	if got := m.fast.of("core1"); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("fastTargets.of() = %v, want [1 3]", got)
+	}
+	if got := m.fast.of("edge1"); len(got) != 0 {
+		t.Errorf("fastTargets.of() of a host without marks = %v", got)
+	}
+}
@@ -0,0 +1,341 @@
+package trmon
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filterFunc reports whether an interface line matches a filter expression.
+//
+// A filter expression combines predicates with and, or, not and parentheses.
+// A predicate is either "<field><op><value>" or a bare regexp which is
+// matched against "I/F Description" like the former -e option.
+//
+//	host=~core.* and in_util>50 and not status=Down
+type filterFunc func(r ifRow) bool
+
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	numberField
+)
+
+type field struct {
+	kind fieldKind
+	str  func(r ifRow) string
+	num  func(r ifRow) float64
+}
+
+// filterFields are the fields which can be used in predicates.
+var filterFields = map[string]field{
+	"host":      {kind: stringField, str: func(r ifRow) string { return r.host.Name }},
+	"if":        {kind: stringField, str: func(r ifRow) string { return r.ifc.Desc }},
+	"sysname":   {kind: stringField, str: func(r ifRow) string { return sysNameOf(r.host) }},
+	"ifname":    {kind: stringField, str: func(r ifRow) string { return r.ifc.Name }},
+	"desc":      {kind: stringField, str: func(r ifRow) string { return r.ifc.Alias }},
+	"status":    {kind: stringField, str: func(r ifRow) string { return r.ifc.OperStatus }},
+	"admin":     {kind: stringField, str: func(r ifRow) string { return r.ifc.AdminStatus }},
+	"neighbor":  {kind: stringField, str: func(r ifRow) string { return r.ifc.neighbor() }},
+	"speed":     {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.Speed) }},
+	"in":        {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.InOctets.Rate * 8) }},
+	"out":       {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.OutOctets.Rate * 8) }},
+	"in_pps":    {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.inPkts()) }},
+	"out_pps":   {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.outPkts()) }},
+	"in_mcast":  {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.InMcastPkts.Rate) }},
+	"out_mcast": {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.OutMcastPkts.Rate) }},
+	"in_bcast":  {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.InBcastPkts.Rate) }},
+	"out_bcast": {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.OutBcastPkts.Rate) }},
+	"in_util":   {kind: numberField, num: func(r ifRow) float64 { return utilization(r.ifc.InOctets, r.ifc.Speed) }},
+	"out_util":  {kind: numberField, num: func(r ifRow) float64 { return utilization(r.ifc.OutOctets, r.ifc.Speed) }},
+	"rx_power":  {kind: numberField, num: func(r ifRow) float64 { return opticsValue(r.ifc.Optics, func(o *Optics) float64 { return o.RxPower }) }},
+	"tx_power":  {kind: numberField, num: func(r ifRow) float64 { return opticsValue(r.ifc.Optics, func(o *Optics) float64 { return o.TxPower }) }},
+	"temp": {kind: numberField, num: func(r ifRow) float64 {
+		return opticsValue(r.ifc.Optics, func(o *Optics) float64 { return o.Temperature })
+	}},
+	"cpu": {kind: numberField, num: func(r ifRow) float64 { return healthValue(r.host.Health, func(hl *Health) float64 { return hl.CPU }) }},
+	"mem": {kind: numberField, num: func(r ifRow) float64 {
+		return healthValue(r.host.Health, func(hl *Health) float64 { return hl.Memory })
+	}},
+	"in_err":  {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.InError.Diff) }},
+	"out_err": {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.OutError.Diff) }},
+	"in_dis":  {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.InDiscards.Diff) }},
+	"out_dis": {kind: numberField, num: func(r ifRow) float64 { return float64(r.ifc.OutDiscards.Diff) }},
+}
+
+// utilization returns the percentage of the octets counter rate in speed [bps].
+func utilization(c *Counter, speed int64) float64 {
+	if speed == 0 {
+		return 0
+	}
+	return float64(c.Rate*8) * 100 / float64(speed)
+}
+
+// opticsValue returns NaN, which matches no comparison, without readings.
+func opticsValue(o *Optics, v func(o *Optics) float64) float64 {
+	if o == nil {
+		return math.NaN()
+	}
+	return v(o)
+}
+
+func sysNameOf(h *Host) string {
+	if h.Health == nil {
+		return ""
+	}
+	return h.Health.SysName
+}
+
+func healthValue(hl *Health, v func(hl *Health) float64) float64 {
+	if hl == nil {
+		return math.NaN()
+	}
+	return v(hl)
+}
+
+var filterOps = []string{"=~", "!~", "!=", ">=", "<=", "=", ">", "<"}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+// parseFilter compiles a filter expression. An empty expression matches nothing.
+func parseFilter(expr string) (filterFunc, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return func(r ifRow) bool { return false }, nil
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+// tokenizeFilter splits expr into parentheses and words. Double quotes keep
+// spaces and parentheses in a word, and parentheses balanced inside a word
+// such as a regexp group belong to the word.
+func tokenizeFilter(expr string) ([]string, error) {
+	tokens := make([]string, 0)
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		switch {
+		case unicode.IsSpace(rs[i]):
+			i++
+		case rs[i] == '(' || rs[i] == ')':
+			tokens = append(tokens, string(rs[i]))
+			i++
+		default:
+			var b strings.Builder
+			depth := 0
+			quoted := false
+		word:
+			for ; i < len(rs); i++ {
+				switch {
+				case rs[i] == '"':
+					quoted = !quoted
+				case quoted:
+					b.WriteRune(rs[i])
+				case unicode.IsSpace(rs[i]):
+					break word
+				case rs[i] == '(':
+					depth++
+					b.WriteRune(rs[i])
+				case rs[i] == ')':
+					if depth == 0 {
+						break word
+					}
+					depth--
+					b.WriteRune(rs[i])
+				default:
+					b.WriteRune(rs[i])
+				}
+			}
+			if quoted {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			tokens = append(tokens, b.String())
+		}
+	}
+	return tokens, nil
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) or() (filterFunc, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r ifRow) bool { return l(r) || right(r) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) and() (filterFunc, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r ifRow) bool { return l(r) && right(r) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) not() (filterFunc, error) {
+	if p.peek() == "not" {
+		p.pos++
+		f, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(r ifRow) bool { return !f(r) }, nil
+	}
+	return p.primary()
+}
+
+func (p *filterParser) primary() (filterFunc, error) {
+	switch tok := p.peek(); tok {
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case "(":
+		p.pos++
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return f, nil
+	case ")", "and", "or":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	default:
+		p.pos++
+		return predicate(tok)
+	}
+}
+
+// predicate compiles "<field><op><value>" or a bare regexp.
+func predicate(tok string) (filterFunc, error) {
+	name := strings.TrimLeftFunc(tok, func(c rune) bool { return c == '_' || unicode.IsLetter(c) })
+	name = tok[:len(tok)-len(name)]
+	rest := tok[len(name):]
+	_, known := filterFields[name]
+	for _, op := range filterOps {
+		if known && strings.HasPrefix(rest, op) {
+			return fieldPredicate(name, op, rest[len(op):])
+		}
+	}
+
+	re, err := regexp.Compile(tok)
+	if err != nil {
+		return nil, err
+	}
+	return func(r ifRow) bool {
+		return re.MatchString(fmt.Sprintf("%v %v %v", r.ifc.Desc, r.ifc.Name, r.ifc.Alias))
+	}, nil
+}
+
+func fieldPredicate(name string, op string, value string) (filterFunc, error) {
+	f, ok := filterFields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %v", name)
+	}
+	if f.kind == stringField {
+		switch op {
+		case "=":
+			return func(r ifRow) bool { return strings.EqualFold(f.str(r), value) }, nil
+		case "!=":
+			return func(r ifRow) bool { return !strings.EqualFold(f.str(r), value) }, nil
+		case "=~", "!~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			want := op == "=~"
+			return func(r ifRow) bool { return re.MatchString(f.str(r)) == want }, nil
+		}
+		return nil, fmt.Errorf("%v can't be used with %v", op, name)
+	}
+
+	v, err := parseNumber(value)
+	if err != nil {
+		return nil, fmt.Errorf("%v needs a number: %v", name, err)
+	}
+	var cmp func(n float64) bool
+	switch op {
+	case "=":
+		cmp = func(n float64) bool { return n == v }
+	case "!=":
+		cmp = func(n float64) bool { return n != v }
+	case ">":
+		cmp = func(n float64) bool { return n > v }
+	case ">=":
+		cmp = func(n float64) bool { return n >= v }
+	case "<":
+		cmp = func(n float64) bool { return n < v }
+	case "<=":
+		cmp = func(n float64) bool { return n <= v }
+	default:
+		return nil, fmt.Errorf("%v can't be used with %v", op, name)
+	}
+	// a missing reading is NaN and matches nothing, not even !=
+	return func(r ifRow) bool {
+		n := f.num(r)
+		return !math.IsNaN(n) && cmp(n)
+	}, nil
+}
+
+// parseNumber parses a number with an optional k, M or G suffix.
+func parseNumber(s string) (float64, error) {
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		scale = 1000
+	case strings.HasSuffix(s, "M"):
+		scale = 1000 * 1000
+	case strings.HasSuffix(s, "G"):
+		scale = 1000 * 1000 * 1000
+	}
+	if scale != 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * scale, nil
+}
@@ -0,0 +1,149 @@
+package trmon
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_parseFilter(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	core := newHost("core1", "", l)
+	edge := newHost("edge1", "", l)
+
+	uplink := NewIF(1, l)
+	uplink.Desc = "ge-0/0/1"
+	uplink.Alias = "uplink (to isp)"
+	uplink.OperStatus = "UP"
+	uplink.Speed = 1000 * 1000 * 1000
+	uplink.InOctets.Rate = 100 * 1000 * 1000 / 8
+	uplink.InUcastPkts.Rate = 900
+	uplink.InBcastPkts.Rate = 2000
+
+	down := NewIF(2, l)
+	down.Desc = "ge-0/0/2"
+	down.OperStatus = "Down"
+
+	vlan := NewIF(3, l)
+	vlan.Desc = "irb"
+	vlan.Alias = "vlan=10"
+
+	tests := []struct {
+		name    string
+		expr    string
+		row     ifRow
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "empty matches nothing",
+			expr: "",
+			row:  ifRow{core, uplink},
+			want: false,
+		},
+		{
+			name: "bare regexp",
+			expr: "ge-0/0/[0-1]",
+			row:  ifRow{core, uplink},
+			want: true,
+		},
+		{
+			name: "bare regexp matches description",
+			expr: `"uplink \(to isp"`,
+			row:  ifRow{core, uplink},
+			want: true,
+		},
+		{
+			name: "regexp group in a value",
+			expr: "host=~(core|dist)1",
+			row:  ifRow{core, uplink},
+			want: true,
+		},
+		{
+			name: "broadcast rate",
+			expr: "in_bcast>=2k",
+			row:  ifRow{core, uplink},
+			want: true,
+		},
+		{
+			name: "pps counts every packet",
+			expr: "in_pps=2900",
+			row:  ifRow{core, uplink},
+			want: true,
+		},
+		{
+			name: "string equal ignores case",
+			expr: "status=down",
+			row:  ifRow{core, down},
+			want: true,
+		},
+		{
+			name: "and or not with parentheses",
+			expr: "host=~core.* and (in_util>=10 or in>1G) and not status=Down",
+			row:  ifRow{core, uplink},
+			want: true,
+		},
+		{
+			name: "and short circuits to false",
+			expr: "host=~core.* and in_util>50",
+			row:  ifRow{core, uplink},
+			want: false,
+		},
+		{
+			name: "number suffix",
+			expr: "in>=100M",
+			row:  ifRow{edge, uplink},
+			want: true,
+		},
+		{
+			name: "unknown field is a bare regexp",
+			expr: "vlan=10",
+			row:  ifRow{core, vlan},
+			want: true,
+		},
+		{
+			name: "no optics matches no comparison",
+			expr: "rx_power!=-40",
+			row:  ifRow{core, uplink},
+			want: false,
+		},
+		{
+			name:    "regexp operator on number",
+			expr:    "in=~1",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			expr:    "in>fast",
+			wantErr: true,
+		},
+		{
+			name:    "missing paren",
+			expr:    "(status=UP",
+			wantErr: true,
+		},
+		{
+			name:    "dangling and",
+			expr:    "status=UP and",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			expr:    `desc="uplink`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := f(tt.row); got != tt.want {
+				t.Errorf("parseFilter()(row) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package trmon
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+)
+
+// ungrouped is the section name of hosts without a group.
+const ungrouped = "(ungrouped)"
+
+type hostGroup struct {
+	name  string
+	hosts []*Host
+}
+
+// groups returns groups in name order with hosts in the order of m.Hosts.
+// Hosts without a group come last.
+func (m *MainWidget) groups() []hostGroup {
+	index := make(map[string]int)
+	groups := make([]hostGroup, 0)
+	for _, h := range m.Hosts {
+		name := h.Group
+		if name == "" {
+			name = ungrouped
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, hostGroup{name: name})
+		}
+		groups[i].hosts = append(groups[i].hosts, h)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[j].name == ungrouped {
+			return groups[i].name != ungrouped
+		}
+		if groups[i].name == ungrouped {
+			return false
+		}
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// totals sums IN and OUT rates of all interfaces of the hosts. LAG members
+// are left out when their aggregator is counted, not to count them twice.
+func (m *MainWidget) totals(hosts ...*Host) (int64, int64) {
+	var in, out int64
+	for _, h := range hosts {
+		for _, i := range h.IFs {
+			if i.LagIndex != 0 && h.IFs[i.LagIndex] != nil {
+				continue
+			}
+			ri, ro := m.rates(i)
+			in += ri
+			out += ro
+		}
+	}
+	return in, out
+}
+
+func (l tableLine) collapseKey() string {
+	if l.host == nil {
+		return "group:" + l.group
+	}
+	return "host:" + l.host.Name
+}
+
+// toggleCollapse collapses or expands a group or host section.
+func (m *MainWidget) toggleCollapse(l tableLine) {
+	key := l.collapseKey()
+	if m.collapsed[key] {
+		delete(m.collapsed, key)
+		return
+	}
+	m.collapsed[key] = true
+}
+
+func (m *MainWidget) printGrouped(t *tablewriter.Table, marked, narrowed, other []ifRow) {
+	byHost := make(map[*Host][]coloredRow)
+	for _, r := range colorRows(marked, narrowed, other) {
+		byHost[r.host] = append(byHost[r.host], r)
+	}
+
+	for _, g := range m.groups() {
+		gl := tableLine{group: g.name}
+		in, out := m.totals(g.hosts...)
+		m.addLine(t, gl, []string{
+			fmt.Sprintf("%v %v", m.fold(gl), g.name),
+			"", "",
+			humanize.Comma(in),
+			humanize.Comma(out),
+			"", "", "", "", "",
+			fmt.Sprintf("%v hosts", len(g.hosts)),
+		}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor})
+		if m.collapsed[gl.collapseKey()] {
+			continue
+		}
+
+		for _, h := range g.hosts {
+			hl := tableLine{ifRow: ifRow{h, nil}, group: g.name}
+			if !h.Reachable {
+				row := unreachableRow(h)
+				row[0] = "  " + row[0]
+				m.addLine(t, hl, row, tablewriter.Colors{tablewriter.FgRedColor})
+				continue
+			}
+			m.addLine(t, hl, m.hostRow(h, fmt.Sprintf("  %v %v", m.fold(hl), m.hostName(h))), tablewriter.Colors{tablewriter.Bold})
+			if m.collapsed[hl.collapseKey()] {
+				continue
+			}
+			m.addRows(t, nestLags(byHost[h]))
+		}
+	}
+}
+
+// hostRow is the header line of a host with its totals and health.
+func (m *MainWidget) hostRow(h *Host, name string) []string {
+	in, out := m.totals(h)
+	return []string{
+		name,
+		fmt.Sprintf("%v I/Fs", len(h.IFs)),
+		"",
+		humanize.Comma(in),
+		humanize.Comma(out),
+		"", "", "", "", "",
+		h.Health.summary(),
+	}
+}
+
+func (m *MainWidget) fold(l tableLine) string {
+	if m.collapsed[l.collapseKey()] {
+		return "▶"
+	}
+	return "▼"
+}
@@ -0,0 +1,89 @@
+package trmon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+func TestMainWidget_printGrouped(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h1 := testHost("host1", l)
+	h1.Group = "tokyo"
+	h2 := testHost("host2", l)
+	h3 := testHost("host3", l)
+	h3.Group = "osaka"
+	m := NewMainWidget("main", []*Host{h1, h2, h3}, NewNarrowWidget("filter", "", l), l)
+	m.grouped = true
+
+	tests := []struct {
+		name      string
+		collapsed []string
+		want      []string
+	}{
+		{
+			name: "expanded",
+			want: []string{
+				"group:osaka", "host:host3", "host3/1", "host3/2", "host3/3",
+				"group:tokyo", "host:host1", "host1/1", "host1/2", "host1/3",
+				"group:" + ungrouped, "host:host2", "host2/1", "host2/2", "host2/3",
+			},
+		},
+		{
+			name:      "collapsed group and host",
+			collapsed: []string{"group:osaka", "host:host1"},
+			want: []string{
+				"group:osaka",
+				"group:tokyo", "host:host1",
+				"group:" + ungrouped, "host:host2", "host2/1", "host2/2", "host2/3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.collapsed = make(map[string]bool)
+			for _, k := range tt.collapsed {
+				m.collapsed[k] = true
+			}
+			var b strings.Builder
+			m.displayed = m.displayed[:0]
+			marked, narrowed, other, _ := m.rows()
+			m.printGrouped(tablewriter.NewWriter(&b), marked, narrowed, other)
+
+			got := make([]string, 0, len(m.displayed))
+			for _, l := range m.displayed {
+				if l.ifc != nil {
+					got = append(got, fmt.Sprintf("%v/%v", l.host.Name, l.ifc.Index))
+					continue
+				}
+				got = append(got, l.collapseKey())
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("MainWidget.displayed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMainWidget_totals(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	m := NewMainWidget("main", nil, NewNarrowWidget("filter", "", l), l)
+	in, out := m.totals(testHost("host1", l), testHost("host2", l))
+	// (10+20+30) * 8 bps on each host
+	if in != 960 || out != 960 {
+		t.Errorf("MainWidget.totals() = %v, %v, want 960, 960", in, out)
+	}
+
+	lag := testHost("lag1", l)
+	lag.IFs[2].LagIndex = 1
+	lag.IFs[3].LagIndex = 9
+	in, out = m.totals(lag)
+	// eth1 is counted by its aggregator eth0, and eth2 has no aggregator
+	// in the host
+	if in != (10+30)*8 || out != (30+10)*8 {
+		t.Errorf("MainWidget.totals() of a LAG = %v, %v, want 320, 320", in, out)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/jroimartin/gocui"
 )
 
-func setKeybindgings(g *gocui.Gui, mw *MainWidget, nw *NarrowWidget) {
+func setKeybindgings(g *gocui.Gui, a *App, mw *MainWidget, nw *NarrowWidget, cw *CommandWidget) {
 	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
 		log.Panicln(err)
 	}
@@ -27,7 +27,7 @@ func setKeybindgings(g *gocui.Gui, mw *MainWidget, nw *NarrowWidget) {
 	if err := g.SetKeybinding("main", gocui.KeyArrowDown, gocui.ModNone, downCursor); err != nil {
 		log.Panicln(err)
 	}
-	if err := g.SetKeybinding("main", '/', gocui.ModNone, changeRegexp); err != nil {
+	if err := g.SetKeybinding("main", '/', gocui.ModNone, changeFilter); err != nil {
 		log.Panicln(err)
 	}
 	if err := g.SetKeybinding("main", gocui.KeyCtrlD, gocui.ModNone, pageDown); err != nil {
@@ -45,19 +45,60 @@ func setKeybindgings(g *gocui.Gui, mw *MainWidget, nw *NarrowWidget) {
 	if err := g.SetKeybinding("main", 'p', gocui.ModNone, togglebps(mw)); err != nil {
 		log.Panicln(err)
 	}
+	if err := g.SetKeybinding("main", 'b', gocui.ModNone, toggleBreakdown(mw)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("main", 'g', gocui.ModNone, toggleGroup(mw)); err != nil {
+		log.Panicln(err)
+	}
 	if err := g.SetKeybinding("main", 'h', gocui.ModNone, createHelp); err != nil {
 		log.Panicln(err)
 	}
 	if err := g.SetKeybinding("main", gocui.KeyEnter, gocui.ModNone, toggleMark(mw)); err != nil {
 		log.Panicln(err)
 	}
+	if err := g.SetKeybinding("main", 'i', gocui.ModNone, createDetail(mw)); err != nil {
+		log.Panicln(err)
+	}
+	for _, key := range []interface{}{'q', 'i', gocui.KeyEsc} {
+		if err := g.SetKeybinding(detailView, key, gocui.ModNone, terminateDetail(mw)); err != nil {
+			log.Panicln(err)
+		}
+	}
+	if err := g.SetKeybinding("main", 'a', gocui.ModNone, createAddHost); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("main", 'x', gocui.ModNone, removeHost(a)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("main", 'r', gocui.ModNone, reloadConfig(a)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("addhost", gocui.KeyEnter, gocui.ModNone, addHost(a)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("addhost", gocui.KeyEsc, gocui.ModNone, terminateAddHost); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("main", ':', gocui.ModNone, createCommandLine(cw)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding(cw.Name, gocui.KeyEnter, gocui.ModNone, execCommand(cw)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding(cw.Name, gocui.KeyEsc, gocui.ModNone, terminateCommandLine(cw)); err != nil {
+		log.Panicln(err)
+	}
 	if err := g.SetKeybinding("help", 'q', gocui.ModNone, terminateHelp); err != nil {
 		log.Panicln(err)
 	}
 	if err := g.SetKeybinding("help", 'h', gocui.ModNone, terminateHelp); err != nil {
 		log.Panicln(err)
 	}
-	if err := g.SetKeybinding("regexp", gocui.KeyEnter, gocui.ModNone, (nallowRegexp(nw))); err != nil {
+	if err := g.SetKeybinding("main", 'f', gocui.ModNone, toggleFilterMode(nw)); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("filter", gocui.KeyEnter, gocui.ModNone, narrowFilter(nw)); err != nil {
 		log.Panicln(err)
 	}
 }
@@ -131,17 +172,21 @@ func pageUp(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
-func changeRegexp(g *gocui.Gui, v *gocui.View) error {
-	if _, err := g.SetCurrentView("regexp"); err != nil {
+func changeFilter(g *gocui.Gui, v *gocui.View) error {
+	if _, err := g.SetCurrentView("filter"); err != nil {
 		return err
 	}
 	return nil
 }
 
-func nallowRegexp(nw *NarrowWidget) func(g *gocui.Gui, v *gocui.View) error {
+func narrowFilter(nw *NarrowWidget) func(g *gocui.Gui, v *gocui.View) error {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		expr, _ := v.Line(0)
-		nw.setRegexp(expr)
+		// Stay in the input line to fix the expression
+		if err := nw.setFilter(expr); err != nil {
+			nw.log.Debug().Msgf("invalid filter %v: %v", expr, err)
+			return nil
+		}
 		if _, err := g.SetCurrentView("main"); err != nil {
 			return err
 		}
@@ -149,6 +194,13 @@ func nallowRegexp(nw *NarrowWidget) func(g *gocui.Gui, v *gocui.View) error {
 	}
 }
 
+func toggleFilterMode(nw *NarrowWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		nw.hide = !nw.hide
+		return nil
+	}
+}
+
 func createHelp(g *gocui.Gui, v *gocui.View) error {
 	maxX, maxY := g.Size()
 	v, err := g.SetView("help", maxX/10, maxY/5, maxX*8/10, maxY*5/6)
@@ -185,6 +237,20 @@ func toggleDownIF(mw *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
 	}
 }
 
+func toggleGroup(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		m.grouped = !m.grouped
+		return nil
+	}
+}
+
+func toggleBreakdown(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		m.breakdown = !m.breakdown
+		return nil
+	}
+}
+
 func toggleUnit(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		switch m.unit {
@@ -220,30 +286,166 @@ func togglebps(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
 
 func toggleMark(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
 	return func(g *gocui.Gui, v *gocui.View) error {
-		_, y := v.Cursor()
-		line, err := v.Line(y)
-		if err != nil {
-			m.log.Debug().Msg("Failed to get line")
+		r, ok := m.rowAt(v)
+		if !ok {
+			m.log.Debug().Msg("No line at the cursor")
+			return nil
+		}
+		if r.ifc == nil {
+			if m.grouped {
+				m.toggleCollapse(r)
+			}
+			return nil
+		}
+		m.toggleMark(r.key())
+		return nil
+	}
+}
+
+func createDetail(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		r, ok := m.rowAt(v)
+		if !ok || r.ifc == nil {
+			return nil
+		}
+		key := r.key()
+		m.detail = &key
+		if err := m.layoutDetail(g); err != nil {
+			return err
+		}
+		if _, err := g.SetCurrentView(detailView); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+func terminateDetail(m *MainWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		m.detail = nil
+		if _, err := g.SetCurrentView(m.Name); err != nil {
+			return err
+		}
+		if err := g.DeleteView(detailView); err != nil {
 			return err
 		}
-		parsed := strings.Split(line, "|")
-		if len(parsed) < 2 {
-			m.log.Debug().Msg("Failed to parse mark line via |")
+		return nil
+	}
+}
+
+func createAddHost(g *gocui.Gui, v *gocui.View) error {
+	maxX, maxY := g.Size()
+	v, err := g.SetView("addhost", maxX/10, maxY/2-1, maxX*8/10, maxY/2+1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "add host: [group/]<agent> [community]"
+		v.Editable = true
+		v.Editor = &Editor{}
+	}
+	if _, err := g.SetCurrentView("addhost"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func terminateAddHost(g *gocui.Gui, v *gocui.View) error {
+	if _, err := g.SetCurrentView("main"); err != nil {
+		return err
+	}
+	if err := g.DeleteView("addhost"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func addHost(a *App) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		line, _ := v.Line(0)
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			return terminateAddHost(g, v)
+		}
+		hc := ParseAgent(args[0])
+		if len(args) > 1 {
+			hc.Community = args[1]
+		}
+		if err := a.addHost(hc); err != nil {
+			a.log.Warn().Msgf("%v", err)
+			v.Title = err.Error()
+			return nil
+		}
+		return terminateAddHost(g, v)
+	}
+}
+
+func reloadConfig(a *App) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		a.reloadNotify(g)
+		return nil
+	}
+}
+
+func removeHost(a *App) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		r, ok := a.mw.rowAt(v)
+		if !ok || r.host == nil {
+			a.log.Debug().Msg("No host at the cursor")
 			return nil
 		}
-		host := strings.Trim(parsed[0], " \t")
-		ifname := strings.Trim(parsed[1], " \t")
-		for i, v := range m.Markeds {
-			// Delete if already marked
-			if v.Host == host && v.IF == ifname {
-				m.log.Debug().Msgf("delete marked host %v, if %v", host, ifname)
-				m.Markeds[i] = m.Markeds[len(m.Markeds)-1]
-				m.Markeds = m.Markeds[:len(m.Markeds)-1]
-				return nil
+		if err := a.removeHost(r.host.Name); err != nil {
+			a.log.Warn().Msgf("%v", err)
+		}
+		return nil
+	}
+}
+
+func createCommandLine(cw *CommandWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		maxX, maxY := g.Size()
+		v, err := g.SetView(cw.Name, 0, maxY-2, maxX-2, maxY)
+		if err != nil {
+			if err != gocui.ErrUnknownView {
+				return err
 			}
+			v.Title = ":"
+			v.Editable = true
+			v.Editor = cw
+		}
+		if _, err := g.SetCurrentView(cw.Name); err != nil {
+			return err
 		}
-		m.log.Debug().Msgf("mark host %v, if %v", host, ifname)
-		m.Markeds = append(m.Markeds, marked{host, ifname})
 		return nil
 	}
 }
+
+func terminateCommandLine(cw *CommandWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		// Commands such as help may have moved the focus already
+		if cv := g.CurrentView(); cv != nil && cv.Name() == cw.Name {
+			if _, err := g.SetCurrentView("main"); err != nil {
+				return err
+			}
+		}
+		if err := g.DeleteView(cw.Name); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+func execCommand(cw *CommandWidget) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		line, _ := v.Line(0)
+		if err := cw.exec(g, line); err != nil {
+			if err == gocui.ErrQuit {
+				return err
+			}
+			cw.log.Warn().Msgf("%v", err)
+			v.Title = err.Error()
+			return nil
+		}
+		return terminateCommandLine(cw)(g, v)
+	}
+}
@@ -0,0 +1,258 @@
+package trmon
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// SNMPv2-MIB
+	sysDescr  string = ".1.3.6.1.2.1.1.1.0"
+	sysUpTime string = ".1.3.6.1.2.1.1.3.0"
+	sysName   string = ".1.3.6.1.2.1.1.5.0"
+
+	// HOST-RESOURCES-MIB
+	hrProcessorLoad         string = ".1.3.6.1.2.1.25.3.3.1.2"
+	hrStorageEntry          string = ".1.3.6.1.2.1.25.2.3.1"
+	hrStorageType           string = ".1.3.6.1.2.1.25.2.3.1.2."
+	hrStorageAllocationUnit string = ".1.3.6.1.2.1.25.2.3.1.4."
+	hrStorageSize           string = ".1.3.6.1.2.1.25.2.3.1.5."
+	hrStorageUsed           string = ".1.3.6.1.2.1.25.2.3.1.6."
+	hrStorageRam            string = ".1.3.6.1.2.1.25.2.1.2"
+)
+
+// healthInterval is how often the health of the device is read. It is
+// shown by the minute, so it is not read on every poll.
+const healthInterval = time.Minute
+
+// Health is the state of the device itself. CPU and Memory are NaN when
+// the agent reports neither HOST-RESOURCES-MIB nor its profile.
+type Health struct {
+	SysDescr string
+	SysName  string
+	UpTime   time.Duration
+	// CPU and Memory are usage in %
+	CPU    float64
+	Memory float64
+}
+
+// HealthProfile maps vendor columns for agents without HOST-RESOURCES-MIB.
+// CPU and Memory are columns in %, of which the busiest row is taken.
+// MemUsed and MemFree are columns in any unit, summed over the rows.
+type HealthProfile struct {
+	Name    string `json:"name"`
+	CPU     string `json:"cpu"`
+	Memory  string `json:"memory"`
+	MemUsed string `json:"mem_used"`
+	MemFree string `json:"mem_free"`
+}
+
+// builtinHealthProfiles can be given to an agent without defining them in
+// the configuration file.
+var builtinHealthProfiles = []HealthProfile{
+	{
+		// CISCO-PROCESS-MIB cpmCPUTotal5minRev, CISCO-MEMORY-POOL-MIB
+		Name:    "cisco",
+		CPU:     ".1.3.6.1.4.1.9.9.109.1.1.1.1.8",
+		MemUsed: ".1.3.6.1.4.1.9.9.48.1.1.1.5",
+		MemFree: ".1.3.6.1.4.1.9.9.48.1.1.1.6",
+	},
+	{
+		// JUNIPER-MIB jnxOperatingCPU, jnxOperatingBuffer
+		Name:   "juniper",
+		CPU:    ".1.3.6.1.4.1.2636.3.1.13.1.8",
+		Memory: ".1.3.6.1.4.1.2636.3.1.13.1.11",
+	},
+}
+
+// updateHealth gets the system group and the CPU and memory usage once
+// healthInterval has passed since the last time.
+func (c *SNMPCollector) updateHealth() {
+	if time.Since(c.healthAt) < healthInterval {
+		return
+	}
+	c.healthAt = time.Now()
+
+	hl := &Health{CPU: math.NaN(), Memory: math.NaN()}
+	result, err := c.params.Get([]string{sysDescr, sysUpTime, sysName})
+	if err != nil {
+		c.log.Debug().Msgf("Failed to Get system: %v", err)
+		c.fail(err)
+	} else {
+		for _, pdu := range result.Variables {
+			switch pdu.Name {
+			case sysDescr:
+				hl.SysDescr = firstLine(octetString(pdu.Value))
+			case sysUpTime:
+				// TimeTicks are hundredths of a second
+				hl.UpTime = time.Duration(gosnmp.ToBigInt(pdu.Value).Int64()) * 10 * time.Millisecond
+			case sysName:
+				hl.SysName = octetString(pdu.Value)
+			}
+		}
+	}
+
+	if p := c.HealthProfile; p != nil {
+		c.walkHealthProfile(hl, p)
+	} else {
+		c.walkHostResources(hl)
+	}
+	c.health = hl
+}
+
+func (c *SNMPCollector) walkHostResources(hl *Health) {
+	var loads []float64
+	err := c.bulkWalk(hrProcessorLoad, func(pdu gosnmp.SnmpPDU) error {
+		loads = append(loads, float64(gosnmp.ToBigInt(pdu.Value).Int64()))
+		return nil
+	})
+	if err != nil {
+		c.log.Debug().Msgf("Failed to Update hrProcessorLoad: %v", err)
+	}
+	hl.CPU = mean(loads)
+
+	s := newStorageTable()
+	if err := c.bulkWalk(hrStorageEntry, s.value); err != nil {
+		c.log.Debug().Msgf("Failed to Update hrStorageEntry: %v", err)
+	}
+	hl.Memory = s.ramUsage()
+}
+
+func (c *SNMPCollector) walkHealthProfile(hl *Health, p *HealthProfile) {
+	walk := func(oid string) []float64 {
+		var values []float64
+		if oid == "" {
+			return values
+		}
+		err := c.bulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+			values = append(values, float64(gosnmp.ToBigInt(pdu.Value).Int64()))
+			return nil
+		})
+		if err != nil {
+			c.log.Debug().Msgf("Failed to Update %v: %v", oid, err)
+		}
+		return values
+	}
+	hl.CPU = busiest(walk(p.CPU))
+	hl.Memory = busiest(walk(p.Memory))
+	if used, free := sum(walk(p.MemUsed)), sum(walk(p.MemFree)); used+free > 0 {
+		hl.Memory = used * 100 / (used + free)
+	}
+}
+
+// storageTable collects hrStorageEntry rows by hrStorageIndex.
+type storageTable struct {
+	ram  map[int]bool
+	unit map[int]int64
+	size map[int]int64
+	used map[int]int64
+}
+
+func newStorageTable() *storageTable {
+	return &storageTable{
+		ram:  make(map[int]bool),
+		unit: make(map[int]int64),
+		size: make(map[int]int64),
+		used: make(map[int]int64),
+	}
+}
+
+func (s *storageTable) value(pdu gosnmp.SnmpPDU) error {
+	index, ok := lastIndex(pdu.Name)
+	if !ok {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(pdu.Name, hrStorageType):
+		oid, _ := pdu.Value.(string)
+		s.ram[index] = oid == hrStorageRam
+	case strings.HasPrefix(pdu.Name, hrStorageAllocationUnit):
+		s.unit[index] = gosnmp.ToBigInt(pdu.Value).Int64()
+	case strings.HasPrefix(pdu.Name, hrStorageSize):
+		s.size[index] = gosnmp.ToBigInt(pdu.Value).Int64()
+	case strings.HasPrefix(pdu.Name, hrStorageUsed):
+		s.used[index] = gosnmp.ToBigInt(pdu.Value).Int64()
+	}
+	return nil
+}
+
+// ramUsage returns the usage of physical memory in %.
+func (s *storageTable) ramUsage() float64 {
+	var size, used float64
+	for index, ram := range s.ram {
+		if !ram {
+			continue
+		}
+		size += float64(s.size[index] * s.unit[index])
+		used += float64(s.used[index] * s.unit[index])
+	}
+	if size == 0 {
+		return math.NaN()
+	}
+	return used * 100 / size
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	return sum(values) / float64(len(values))
+}
+
+// busiest returns the highest value, or NaN without values.
+func busiest(values []float64) float64 {
+	m := math.NaN()
+	for _, v := range values {
+		if math.IsNaN(m) || v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// summary is the health shown in the header line of the host.
+func (hl *Health) summary() string {
+	if hl == nil {
+		return ""
+	}
+	s := make([]string, 0, 3)
+	if !math.IsNaN(hl.CPU) {
+		s = append(s, fmt.Sprintf("CPU %.0f%%", hl.CPU))
+	}
+	if !math.IsNaN(hl.Memory) {
+		s = append(s, fmt.Sprintf("Mem %.0f%%", hl.Memory))
+	}
+	if hl.UpTime > 0 {
+		s = append(s, "up "+formatUptime(hl.UpTime))
+	}
+	return strings.Join(s, " ")
+}
+
+func formatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	if days > 0 {
+		return fmt.Sprintf("%dd%02dh", days, hours)
+	}
+	return fmt.Sprintf("%dh%02dm", hours, minutes)
+}
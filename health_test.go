@@ -0,0 +1,83 @@
+package trmon
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestStorageTable_ramUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		pdus []gosnmp.SnmpPDU
+		want float64
+	}{
+		{
+			name: "ram only",
+			pdus: []gosnmp.SnmpPDU{
+				{Name: hrStorageType + "1", Value: hrStorageRam},
+				{Name: hrStorageAllocationUnit + "1", Value: 1024},
+				{Name: hrStorageSize + "1", Value: 1000},
+				{Name: hrStorageUsed + "1", Value: 250},
+				// a disk is not memory
+				{Name: hrStorageType + "31", Value: ".1.3.6.1.2.1.25.2.1.4"},
+				{Name: hrStorageAllocationUnit + "31", Value: 4096},
+				{Name: hrStorageSize + "31", Value: 1000},
+				{Name: hrStorageUsed + "31", Value: 900},
+			},
+			want: 25,
+		},
+		{
+			name: "no ram",
+			pdus: []gosnmp.SnmpPDU{
+				{Name: hrStorageType + "31", Value: ".1.3.6.1.2.1.25.2.1.4"},
+			},
+			want: math.NaN(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorageTable()
+			for _, pdu := range tt.pdus {
+				s.value(pdu)
+			}
+			got := s.ramUsage()
+			if got != tt.want && !(math.IsNaN(got) && math.IsNaN(tt.want)) {
+				t.Errorf("storageTable.ramUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealth_summary(t *testing.T) {
+	tests := []struct {
+		name   string
+		health *Health
+		want   string
+	}{
+		{
+			name:   "not polled",
+			health: nil,
+			want:   "",
+		},
+		{
+			name:   "everything",
+			health: &Health{CPU: 12.4, Memory: 45.6, UpTime: 76*time.Hour + 30*time.Minute},
+			want:   "CPU 12% Mem 46% up 3d04h",
+		},
+		{
+			name:   "uptime only",
+			health: &Health{CPU: math.NaN(), Memory: math.NaN(), UpTime: 90 * time.Minute},
+			want:   "up 1h30m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.health.summary(); got != tt.want {
+				t.Errorf("Health.summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,52 +1,78 @@
 package trmon
 
 import (
-	"strconv"
-	"strings"
+	"io"
+	"sync"
 	"time"
-
-	"github.com/gosnmp/gosnmp"
 )
 
-const (
-	ifDescr          string = ".3.6.1.2.1.2.2.1.2."
-	ifAlias          string = ".3.6.1.2.1.31.1.1.1.18."
-	ifSpeed          string = ".3.6.1.2.1.2.2.1.5."
-	ifAdminStatus    string = ".3.6.1.2.1.2.2.1.7"
-	ifOperStatus     string = ".3.6.1.2.1.2.2.1.8"
-	ifHCInOctets     string = ".3.6.1.2.1.31.1.1.1.6."
-	ifHCOutOctets    string = ".3.6.1.2.1.31.1.1.1.10."
-	ifHCInUcastPkts  string = ".3.6.1.2.1.31.1.1.1.7."
-	ifHCOutUcastPkts string = ".3.6.1.2.1.31.1.1.1.11."
-	ifInDiscards     string = ".3.6.1.2.1.2.2.1.13."
-	ifOutDiscards    string = ".3.6.1.2.1.2.2.1.19."
-	ifInErrors       string = ".3.6.1.2.1.2.2.1.14."
-	ifOutErrors      string = ".3.6.1.2.1.2.2.1.20."
-
-	ifIndex  string = ".1.3.6.1.2.1.2.2.1.1"
-	ifEntry  string = ".1.3.6.1.2.1.2.2.1"
-	ifXEntry string = ".1.3.6.1.2.1.31.1.1.1"
-)
+// minRateInterval is the shortest time between two reads of a counter to
+// tell its rate.
+const minRateInterval = 10 * time.Millisecond
 
 type Host struct {
-	Name   string
-	IFs    map[int]*IF
-	params *gosnmp.GoSNMP
-	log    *Logger
+	Name  string
+	Group string
+	// IFs, Reachable, Health, PollTime and PolledAt are read by the UI.
+	// They are only replaced by apply, from what the polling goroutine
+	// published last.
+	IFs map[int]*IF
+	// Reachable is false until the interface table has been discovered.
+	Reachable bool
+	// Health is nil until the first poll
+	Health *Health
+	// PollTime is how long the last poll took, finished at PolledAt
+	PollTime time.Duration
+	PolledAt time.Time
+	// virtual hosts are computed from other hosts and never polled
+	virtual   bool
+	collector Collector
+	// ifs and reachable are of the polling goroutine, and the collector
+	// updates ifs in place
+	ifs       map[int]*IF
+	reachable bool
+	// next is the snapshot published for apply, nil once applied, and
+	// pollTime and polledAt are of the last poll
+	mu       sync.Mutex
+	next     *hostSnapshot
+	pollTime time.Duration
+	polledAt time.Time
+	log      *Logger
+}
+
+// hostSnapshot is the state of a host after a poll, copied so that later
+// polls do not change it.
+type hostSnapshot struct {
+	ifs       map[int]*IF
+	reachable bool
+	health    *Health
 }
 
 type IF struct {
-	Name         string
-	Index        int
-	Speed        int64
-	Desc         string
-	Alias        string
-	AdminStatus  string
-	OperStatus   string
+	Name        string
+	Index       int
+	Speed       int64
+	Desc        string
+	Alias       string
+	AdminStatus string
+	OperStatus  string
+	// LagIndex is the ifIndex of the aggregator the port is attached to,
+	// or 0 when the port is not a LAG member.
+	LagIndex int
+	// Neighbors is refreshed every neighborInterval
+	Neighbors []Neighbor
+	// Dot3 is nil unless the error breakdown is polled and supported
+	Dot3 *Dot3Stats
+	// Optics is nil unless DOM is polled and reported
+	Optics       *Optics
 	InOctets     *Counter
 	OutOctets    *Counter
 	InUcastPkts  *Counter
 	OutUcastPkts *Counter
+	InMcastPkts  *Counter
+	InBcastPkts  *Counter
+	OutMcastPkts *Counter
+	OutBcastPkts *Counter
 	InDiscards   *Counter
 	OutDiscards  *Counter
 	InError      *Counter
@@ -71,7 +97,9 @@ func newCounter(name string, log *Logger) *Counter {
 	}
 }
 
-func newIF(index int, l *Logger) *IF {
+// NewIF returns an interface with all its counters. Collectors create
+// interfaces with it, as the table reads every counter.
+func NewIF(index int, l *Logger) *IF {
 	i := new(IF)
 	i.Index = index
 	i.AdminStatus = ""
@@ -80,6 +108,10 @@ func newIF(index int, l *Logger) *IF {
 	i.OutOctets = newCounter("OutOctets", l)
 	i.InUcastPkts = newCounter("InUcastPkts", l)
 	i.OutUcastPkts = newCounter("OutUcastPkts", l)
+	i.InMcastPkts = newCounter("InMcastPkts", l)
+	i.InBcastPkts = newCounter("InBcastPkts", l)
+	i.OutMcastPkts = newCounter("OutMcastPkts", l)
+	i.OutBcastPkts = newCounter("OutBcastPkts", l)
 	i.InDiscards = newCounter("InDiscards", l)
 	i.OutDiscards = newCounter("OutDiscards", l)
 	i.InError = newCounter("InError", l)
@@ -87,110 +119,180 @@ func newIF(index int, l *Logger) *IF {
 	return i
 }
 
-func (h *Host) newIFs(pdu gosnmp.SnmpPDU) error {
+// clone copies the interface with its counters and readings.
+func (i *IF) clone() *IF {
+	c := *i
+	c.Neighbors = append([]Neighbor(nil), i.Neighbors...)
+	if i.Dot3 != nil {
+		c.Dot3 = i.Dot3.clone()
+	}
+	if i.Optics != nil {
+		o := *i.Optics
+		c.Optics = &o
+	}
+	c.InOctets = i.InOctets.clone()
+	c.OutOctets = i.OutOctets.clone()
+	c.InUcastPkts = i.InUcastPkts.clone()
+	c.OutUcastPkts = i.OutUcastPkts.clone()
+	c.InMcastPkts = i.InMcastPkts.clone()
+	c.InBcastPkts = i.InBcastPkts.clone()
+	c.OutMcastPkts = i.OutMcastPkts.clone()
+	c.OutBcastPkts = i.OutBcastPkts.clone()
+	c.InDiscards = i.InDiscards.clone()
+	c.OutDiscards = i.OutDiscards.clone()
+	c.InError = i.InError.clone()
+	c.OutError = i.OutError.clone()
+	return &c
+}
 
-	index := int(gosnmp.ToBigInt(pdu.Value).Int64())
-	h.IFs[index] = newIF(index, h.log)
+func (c *Counter) clone() *Counter {
+	if c == nil {
+		return nil
+	}
+	n := *c
+	return &n
+}
 
-	return nil
+// counters returns all counters of the interface in a fixed order.
+func (i *IF) counters() []*Counter {
+	return []*Counter{
+		i.InOctets,
+		i.OutOctets,
+		i.InUcastPkts,
+		i.OutUcastPkts,
+		i.InMcastPkts,
+		i.InBcastPkts,
+		i.OutMcastPkts,
+		i.OutBcastPkts,
+		i.InDiscards,
+		i.OutDiscards,
+		i.InError,
+		i.OutError,
+	}
+}
+
+// inPkts returns the rate of unicast, multicast and broadcast packets received.
+func (i *IF) inPkts() int64 {
+	return i.InUcastPkts.Rate + i.InMcastPkts.Rate + i.InBcastPkts.Rate
+}
+
+// outPkts returns the rate of unicast, multicast and broadcast packets sent.
+func (i *IF) outPkts() int64 {
+	return i.OutUcastPkts.Rate + i.OutMcastPkts.Rate + i.OutBcastPkts.Rate
 }
 
 func NewHost(hostname string, community string, l *Logger) (*Host, error) {
-	h := &Host{
-		Name: hostname,
-		IFs:  make(map[int]*IF),
-		params: &gosnmp.GoSNMP{
-			Target:    hostname,
-			Port:      161,
-			Version:   gosnmp.Version2c,
-			Community: community,
-			Timeout:   time.Duration(3) * time.Second,
-		},
-		log: l,
-	}
-
-	if err := h.params.Connect(); err != nil {
-		h.log.Debug().Msgf("Connect() err: %v", err)
-		return nil, err
-	}
-	defer h.params.Conn.Close()
+	return NewCollectorHost(hostname, newCollector(hostname, community, l), l)
+}
 
-	//GET ALL Interface Index
-	h.log.Debug().Msg("Get ALL Interface Index")
-	if err := h.params.BulkWalk(ifIndex, h.newIFs); err != nil {
-		h.log.Debug().Msgf("Failed to new IFs: %v", err)
+// NewCollectorHost returns a Host whose interfaces are read by c, like
+// a host of a backend other than SNMP.
+func NewCollectorHost(name string, c Collector, l *Logger) (*Host, error) {
+	h := newCollectorHost(name, c, l)
+	if err := h.discover(); err != nil {
 		return nil, err
 	}
+	h.apply()
 	return h, nil
 }
 
+// newHost returns a Host that has not discovered its interfaces yet.
+func newHost(hostname string, community string, l *Logger) *Host {
+	return newCollectorHost(hostname, newCollector(hostname, community, l), l)
+}
+
+func newCollectorHost(name string, c Collector, l *Logger) *Host {
+	return &Host{
+		Name:      name,
+		IFs:       make(map[int]*IF),
+		ifs:       make(map[int]*IF),
+		collector: c,
+		log:       l,
+	}
+}
+
+// newCollector reads this machine for a hostname like "localhost:local",
+// and polls the agent by SNMP otherwise.
+func newCollector(hostname string, community string, l *Logger) Collector {
+	if isLocal(hostname) {
+		return NewLocalCollector("/", l)
+	}
+	return NewSNMPCollector(hostname, community, l)
+}
+
+// discover reads the interface table and marks the host reachable on success.
+func (h *Host) discover() error {
+	ifs, err := h.collector.Discover()
+	if err != nil {
+		return err
+	}
+	h.ifs = ifs
+	h.reachable = true
+	h.publish()
+	return nil
+}
+
+// publish copies the state of the polling goroutine for the UI to apply.
+func (h *Host) publish() {
+	snap := &hostSnapshot{
+		ifs:       make(map[int]*IF, len(h.ifs)),
+		reachable: h.reachable,
+	}
+	for index, i := range h.ifs {
+		snap.ifs[index] = i.clone()
+	}
+	if hl := h.collector.Health(); hl != nil {
+		c := *hl
+		snap.health = &c
+	}
+	h.mu.Lock()
+	h.next = snap
+	h.mu.Unlock()
+}
+
+// polled records how long the poll finished at t took.
+func (h *Host) polled(d time.Duration, t time.Time) {
+	h.mu.Lock()
+	h.pollTime, h.polledAt = d, t
+	h.mu.Unlock()
+}
+
+// apply shows the snapshot last published. It is called from the UI
+// goroutine, which alone reads and replaces IFs, Reachable and Health.
+func (h *Host) apply() {
+	h.mu.Lock()
+	snap := h.next
+	h.next = nil
+	h.PollTime, h.PolledAt = h.pollTime, h.polledAt
+	h.mu.Unlock()
+	if snap == nil {
+		return
+	}
+	h.IFs = snap.ifs
+	h.Reachable = snap.reachable
+	h.Health = snap.health
+}
+
+// close releases the resources of the collector, like a session to the agent.
+func (h *Host) close() {
+	if c, ok := h.collector.(io.Closer); ok {
+		c.Close()
+	}
+}
+
 func (h *Host) Update() {
 	h.log.Debug().Msgf("Update IFs %v", h.Name)
-	if err := h.params.Connect(); err != nil {
-		h.log.Debug().Msgf("Connect() err: %v", err)
-	}
-	defer h.params.Conn.Close()
-
-	//GET ALL Interface Value
-	if err := h.params.BulkWalk(ifEntry, h.updateIFValue); err != nil {
-		h.log.Debug().Msgf("Failed to Update ifEntry: %v", err)
-	}
-	if err := h.params.BulkWalk(ifXEntry, h.updateIFValue); err != nil {
-		h.log.Debug().Msgf("Failed to Update ifXEntry: %v", err)
-	}
-}
-
-// Classify retrived snmp PDU and Set new value to IF array
-func (h *Host) updateIFValue(pdu gosnmp.SnmpPDU) error {
-	h.log.Debug().Msgf("pdu %v", pdu)
-	s := strings.Split(pdu.Name, ".")
-	index, _ := strconv.Atoi(s[len(s)-1])
-	t := time.Now()
-
-	switch {
-	case strings.Contains(pdu.Name, ifDescr):
-		h.IFs[index].Desc = string(pdu.Value.([]byte))
-	case strings.Contains(pdu.Name, ifAlias):
-		h.IFs[index].Alias = string(pdu.Value.([]byte))
-	case strings.Contains(pdu.Name, ifSpeed):
-		h.IFs[index].Speed = gosnmp.ToBigInt(pdu.Value).Int64()
-	case strings.Contains(pdu.Name, ifAdminStatus):
-		switch gosnmp.ToBigInt(pdu.Value).Int64() {
-		case 1:
-			h.IFs[index].AdminStatus = "UP"
-		case 2:
-			h.IFs[index].AdminStatus = "Down"
-		}
-	case strings.Contains(pdu.Name, ifOperStatus):
-		switch gosnmp.ToBigInt(pdu.Value).Int64() {
-		case 1:
-			h.IFs[index].OperStatus = "UP"
-		case 2:
-			h.IFs[index].OperStatus = "Down"
-		}
-	case strings.Contains(pdu.Name, ifHCInOctets):
-		h.IFs[index].InOctets.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifHCOutOctets):
-		h.IFs[index].OutOctets.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifHCInUcastPkts):
-		h.IFs[index].InUcastPkts.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifHCOutUcastPkts):
-		h.IFs[index].OutUcastPkts.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifInDiscards):
-		h.IFs[index].InDiscards.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifOutDiscards):
-		h.IFs[index].OutDiscards.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifInDiscards):
-		h.IFs[index].InDiscards.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifOutErrors):
-		h.IFs[index].OutError.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
-	case strings.Contains(pdu.Name, ifInErrors):
-		h.IFs[index].InError.update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	ifs, err := h.collector.Collect(h.ifs)
+	if err != nil {
+		h.log.Debug().Msgf("Failed to Update %v: %v", h.Name, err)
 	}
-	return nil
+	h.ifs = ifs
+	h.publish()
 }
 
-func (c *Counter) update(v int64, t time.Time) {
+// Update takes the sample v of the counter read at t and computes the
+// difference and rate since the previous sample.
+func (c *Counter) Update(v int64, t time.Time) {
 	c.BeforeTime = c.LastTime
 	c.Before = c.Last
 	c.LastTime = t
@@ -202,12 +304,12 @@ func (c *Counter) update(v int64, t time.Time) {
 	} else {
 		c.Diff = d
 	}
-	//When the time difference is less than one second
-	d := int64((c.LastTime.Sub(c.BeforeTime)).Seconds())
-	if d == 0 {
+	//When the time difference is too short to tell a rate
+	d := c.LastTime.Sub(c.BeforeTime)
+	if d < minRateInterval {
 		c.log.Warn().Msgf("zero devide %v", c.name)
 		c.Rate = 0
 	} else {
-		c.Rate = c.Diff / d
+		c.Rate = int64(float64(c.Diff) / d.Seconds())
 	}
 }
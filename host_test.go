@@ -5,9 +5,12 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/gosnmp/gosnmp"
 )
 
 func TestNewHost(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
 	type args struct {
 		hostname  string
 		community string
@@ -17,20 +20,22 @@ func TestNewHost(t *testing.T) {
 		name    string
 		args    args
 		wantErr bool
+		wantIFs int
 	}{
 		{
 			name: "valid snmp target",
 			args: args{
-				hostname:  "127.0.0.1",
+				hostname:  agent.Target,
 				community: "my_comm",
 				logger:    NewLogger(true, os.Stdout),
 			},
 			wantErr: false,
+			wantIFs: 12,
 		},
 		{
 			name: "can't connect snmp target",
 			args: args{
-				hostname:  "127.0.0.1",
+				hostname:  agent.Target,
 				community: "",
 				logger:    NewLogger(true, os.Stdout),
 			},
@@ -38,17 +43,56 @@ func TestNewHost(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewHost(tt.args.hostname, tt.args.community, tt.args.logger)
+			t.Parallel()
+			h, err := NewHost(tt.args.hostname, tt.args.community, tt.args.logger)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewHost() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if err == nil && len(h.IFs) != tt.wantIFs {
+				t.Errorf("len(Host.IFs) = %v, want %v", len(h.IFs), tt.wantIFs)
+			}
 		})
 	}
 }
 
-func TestCounter_update(t *testing.T) {
+func TestHost_Update(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
+	h, err := NewHost(agent.Target, "my_comm", NewLogger(true, os.Stdout))
+	if err != nil {
+		t.Fatalf("NewHost() error = %v", err)
+	}
+	h.Update()
+	h.apply()
+	eth0 := h.IFs[4]
+	if eth0.Desc != "eth0" || eth0.Alias != "WAN" || eth0.OperStatus != "UP" || eth0.Speed != 100000000 {
+		t.Errorf("IFs[4] = %+v", eth0)
+	}
+	if eth0.InOctets.Last != 1611884919191 {
+		t.Errorf("IFs[4].InOctets.Last = %v, want 1611884919191", eth0.InOctets.Last)
+	}
+
+	diff := eth0.InOctets.Diff
+	agent.set(".1.3.6.1.2.1.31.1.1.1.6.4", gosnmp.Counter64, uint64(1611884919191+1500))
+	agent.set(sysName, gosnmp.OctetString, []byte("renamed"))
+	h.Update()
+	h.apply()
+	// the health is read once in healthInterval
+	if h.Health == nil || h.Health.SysName == "renamed" {
+		t.Errorf("Host.Health = %+v, want the one of the first poll", h.Health)
+	}
+	// the UI keeps the snapshot it was given until the next one is applied
+	if eth0.InOctets.Diff != diff {
+		t.Errorf("a snapshot is changed by a later poll")
+	}
+	if eth0 = h.IFs[4]; eth0.InOctets.Diff != 1500 {
+		t.Errorf("IFs[4].InOctets.Diff = %v, want 1500", eth0.InOctets.Diff)
+	}
+}
+
+func TestCounter_Update(t *testing.T) {
 	type fields struct {
 		name       string
 		Last       int64
@@ -96,6 +140,32 @@ func TestCounter_update(t *testing.T) {
 				Rate:       0,
 			},
 		},
+		{
+			name: "sub-second",
+			fields: fields{
+				name:       "hoge",
+				Last:       100,
+				Before:     0,
+				LastTime:   time.Date(2000, time.December, 10, 10, 1, 1, 0, time.UTC),
+				BeforeTime: time.Date(2000, time.December, 10, 10, 1, 0, 500000000, time.UTC),
+				Diff:       100,
+				Rate:       200,
+				log:        NewLogger(true, os.Stdout),
+			},
+			args: args{
+				v: 200,
+				t: time.Date(2000, time.December, 10, 10, 1, 1, 250000000, time.UTC),
+			},
+			want: fields{
+				name:       "hoge",
+				Last:       200,
+				Before:     100,
+				LastTime:   time.Date(2000, time.December, 10, 10, 1, 1, 250000000, time.UTC),
+				BeforeTime: time.Date(2000, time.December, 10, 10, 1, 1, 0, time.UTC),
+				Diff:       100,
+				Rate:       400,
+			},
+		},
 		{
 			name: "minus diff",
 			fields: fields{
@@ -135,9 +205,9 @@ func TestCounter_update(t *testing.T) {
 				Rate:       tt.fields.Rate,
 				log:        tt.fields.log,
 			}
-			c.update(tt.args.v, tt.args.t)
+			c.Update(tt.args.v, tt.args.t)
 			if !reflect.DeepEqual(c.Rate, tt.want.Rate) {
-				t.Errorf("c.update().Rate = %v, want %v", c.Rate, tt.want.Rate)
+				t.Errorf("c.Update().Rate = %v, want %v", c.Rate, tt.want.Rate)
 			}
 		})
 	}
@@ -0,0 +1,142 @@
+package trmon
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// lagImbalanceRatio is how far a member rate may be from the mean rate of
+// the members before the LAG is flagged as imbalanced.
+const lagImbalanceRatio = 0.5
+
+// lagInterval is how often LAG membership is walked, which rarely changes.
+const lagInterval = 5 * time.Minute
+
+// updateLagMembers walks dot3adAggPortAttachedAggID once lagInterval has
+// passed since the last walk.
+func (c *SNMPCollector) updateLagMembers() {
+	if time.Since(c.lagAt) < lagInterval {
+		return
+	}
+	c.lagAt = time.Now()
+	if err := c.bulkWalk(dot3adAggPortAttachedAggID, c.updateLagMember); err != nil {
+		c.log.Debug().Msgf("Failed to Update dot3adAggPortAttachedAggID: %v", err)
+	}
+}
+
+// updateLagMember sets the aggregator of a port from dot3adAggPortAttachedAggID.
+func (c *SNMPCollector) updateLagMember(pdu gosnmp.SnmpPDU) error {
+	s := strings.Split(pdu.Name, ".")
+	index, _ := strconv.Atoi(s[len(s)-1])
+	i, ok := c.ifs[index]
+	if !ok {
+		return nil
+	}
+	agg := int(gosnmp.ToBigInt(pdu.Value).Int64())
+	// Some agents attach a port to itself while it is not aggregated
+	if agg == index {
+		agg = 0
+	}
+	i.LagIndex = agg
+	return nil
+}
+
+// lagMembers returns the ports attached to the aggregator in ifIndex order.
+func (h *Host) lagMembers(agg int) []*IF {
+	members := make([]*IF, 0)
+	for _, i := range h.IFs {
+		if i.LagIndex == agg {
+			members = append(members, i)
+		}
+	}
+	sortIFs(members)
+	return members
+}
+
+func sortIFs(ifs []*IF) {
+	sort.Slice(ifs, func(i, j int) bool { return ifs[i].Index < ifs[j].Index })
+}
+
+// lagAggregators returns the aggregators having at least one member.
+func lagAggregators(hosts []*Host) map[ifKey]bool {
+	aggs := make(map[ifKey]bool)
+	for _, h := range hosts {
+		for _, i := range h.IFs {
+			if i.LagIndex != 0 {
+				aggs[ifKey{h.Name, i.LagIndex}] = true
+			}
+		}
+	}
+	return aggs
+}
+
+// lagImbalanced reports whether the traffic of the UP members of the
+// aggregator is unevenly spread in either direction.
+func (h *Host) lagImbalanced(agg int) bool {
+	up := make([]*IF, 0)
+	for _, i := range h.lagMembers(agg) {
+		if i.OperStatus == "UP" {
+			up = append(up, i)
+		}
+	}
+	if len(up) < 2 {
+		return false
+	}
+	for _, rate := range []func(i *IF) int64{
+		func(i *IF) int64 { return i.InOctets.Rate },
+		func(i *IF) int64 { return i.OutOctets.Rate },
+	} {
+		var total int64
+		for _, i := range up {
+			total += rate(i)
+		}
+		mean := float64(total) / float64(len(up))
+		if mean == 0 {
+			continue
+		}
+		for _, i := range up {
+			if d := float64(rate(i)) - mean; d > mean*lagImbalanceRatio || -d > mean*lagImbalanceRatio {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coloredRow is an interface line with the color of its class.
+type coloredRow struct {
+	ifRow
+	color int
+	// nested is set on a LAG member shown under its aggregator
+	nested bool
+}
+
+// nestLags moves LAG members right after their aggregator when the
+// aggregator is displayed too.
+func nestLags(rows []coloredRow) []coloredRow {
+	displayed := make(map[ifKey]bool)
+	for _, r := range rows {
+		displayed[r.key()] = true
+	}
+	members := make(map[ifKey][]coloredRow)
+	top := make([]coloredRow, 0, len(rows))
+	for _, r := range rows {
+		agg := ifKey{r.host.Name, r.ifc.LagIndex}
+		if r.ifc.LagIndex != 0 && displayed[agg] {
+			r.nested = true
+			members[agg] = append(members[agg], r)
+			continue
+		}
+		top = append(top, r)
+	}
+	nested := make([]coloredRow, 0, len(rows))
+	for _, r := range top {
+		nested = append(nested, r)
+		nested = append(nested, members[r.key()]...)
+	}
+	return nested
+}
@@ -0,0 +1,151 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// lagHost returns testHost with eth1 and eth2 attached to eth0.
+func lagHost(l *Logger) *Host {
+	h := testHost("core1", l)
+	h.IFs[2].LagIndex = 1
+	h.IFs[3].LagIndex = 1
+	return h
+}
+
+func TestSNMPCollector_updateLagMember(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := testHost("core1", l)
+	c := &SNMPCollector{ifs: h.IFs, log: l}
+	tests := []struct {
+		name  string
+		index string
+		agg   int
+		want  map[int]int
+	}{
+		{
+			name:  "member",
+			index: "2",
+			agg:   1,
+			want:  map[int]int{1: 0, 2: 1, 3: 0},
+		},
+		{
+			name:  "attached to itself",
+			index: "3",
+			agg:   3,
+			want:  map[int]int{1: 0, 2: 1, 3: 0},
+		},
+		{
+			name:  "unknown index",
+			index: "9",
+			agg:   1,
+			want:  map[int]int{1: 0, 2: 1, 3: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdu := gosnmp.SnmpPDU{Name: dot3adAggPortAttachedAggID + "." + tt.index, Type: gosnmp.Integer, Value: tt.agg}
+			if err := c.updateLagMember(pdu); err != nil {
+				t.Fatalf("SNMPCollector.updateLagMember() error = %v", err)
+			}
+			got := make(map[int]int)
+			for index, i := range h.IFs {
+				got[index] = i.LagIndex
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LagIndex = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost_lagImbalanced(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	tests := []struct {
+		name   string
+		modify func(h *Host)
+		want   bool
+	}{
+		{
+			name:   "balanced",
+			modify: func(h *Host) {},
+			want:   false,
+		},
+		{
+			name:   "in rate deviates",
+			modify: func(h *Host) { h.IFs[2].InOctets.Rate = 100 },
+			want:   true,
+		},
+		{
+			name: "only one member up",
+			modify: func(h *Host) {
+				h.IFs[2].InOctets.Rate = 100
+				h.IFs[3].OperStatus = "Down"
+			},
+			want: false,
+		},
+		{
+			name: "no traffic",
+			modify: func(h *Host) {
+				for _, i := range h.IFs {
+					i.InOctets.Rate, i.OutOctets.Rate = 0, 0
+				}
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := lagHost(l)
+			// eth1 and eth2 carry the same traffic
+			h.IFs[2].InOctets.Rate, h.IFs[2].OutOctets.Rate = 20, 20
+			h.IFs[3].InOctets.Rate, h.IFs[3].OutOctets.Rate = 20, 20
+			tt.modify(h)
+			if got := h.lagImbalanced(1); got != tt.want {
+				t.Errorf("Host.lagImbalanced() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNestLags(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := lagHost(l)
+	row := func(index int) coloredRow { return coloredRow{ifRow: ifRow{h, h.IFs[index]}} }
+	tests := []struct {
+		name       string
+		rows       []coloredRow
+		wantIndex  []int
+		wantNested []bool
+	}{
+		{
+			name:       "members follow the aggregator",
+			rows:       []coloredRow{row(3), row(2), row(1)},
+			wantIndex:  []int{1, 3, 2},
+			wantNested: []bool{false, true, true},
+		},
+		{
+			name:       "aggregator not displayed",
+			rows:       []coloredRow{row(3), row(2)},
+			wantIndex:  []int{3, 2},
+			wantNested: []bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nestLags(tt.rows)
+			gotIndex := make([]int, 0, len(got))
+			gotNested := make([]bool, 0, len(got))
+			for _, r := range got {
+				gotIndex = append(gotIndex, r.ifc.Index)
+				gotNested = append(gotNested, r.nested)
+			}
+			if !reflect.DeepEqual(gotIndex, tt.wantIndex) || !reflect.DeepEqual(gotNested, tt.wantNested) {
+				t.Errorf("nestLags() = %v %v, want %v %v", gotIndex, gotNested, tt.wantIndex, tt.wantNested)
+			}
+		})
+	}
+}
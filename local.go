@@ -0,0 +1,211 @@
+package trmon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localSuffix marks an agent read from the kernel of this machine instead
+// of SNMP, like "localhost:local".
+const localSuffix = ":local"
+
+// isLocal reports whether the agent is the machine trmon runs on.
+func isLocal(hostname string) bool {
+	return strings.HasSuffix(hostname, localSuffix)
+}
+
+// netDevStats is a line of /proc/net/dev.
+type netDevStats struct {
+	name                                        string
+	rxBytes, rxPackets, rxErrs, rxDrop, rxMcast int64
+	txBytes, txPackets, txErrs, txDrop          int64
+}
+
+// parseNetDev parses /proc/net/dev, skipping its two header lines.
+func parseNetDev(r io.Reader) ([]netDevStats, error) {
+	stats := make([]netDevStats, 0)
+	s := bufio.NewScanner(r)
+	for line := 0; s.Scan(); line++ {
+		if line < 2 {
+			continue
+		}
+		name, values, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", s.Text())
+		}
+		f := strings.Fields(values)
+		if len(f) < 16 {
+			return nil, fmt.Errorf("too few fields of %v", name)
+		}
+		v := make([]int64, 16)
+		for i := range v {
+			n, err := strconv.ParseInt(f[i], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+		stats = append(stats, netDevStats{
+			name:      strings.TrimSpace(name),
+			rxBytes:   v[0],
+			rxPackets: v[1],
+			rxErrs:    v[2],
+			rxDrop:    v[3],
+			rxMcast:   v[7],
+			txBytes:   v[8],
+			txPackets: v[9],
+			txErrs:    v[10],
+			txDrop:    v[11],
+		})
+	}
+	return stats, s.Err()
+}
+
+// LocalCollector reads the interfaces of this machine from /proc/net/dev
+// and /sys/class/net.
+type LocalCollector struct {
+	// Root is where proc and sys are found, "/" unless testing
+	Root   string
+	health *Health
+	log    *Logger
+}
+
+func NewLocalCollector(root string, l *Logger) *LocalCollector {
+	return &LocalCollector{Root: root, log: l}
+}
+
+func (c *LocalCollector) path(elem ...string) string {
+	root := c.Root
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// sysfs reads an attribute of the interface in /sys/class/net.
+func (c *LocalCollector) sysfs(name string, attr string) string {
+	b, err := os.ReadFile(c.path("sys/class/net", name, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// Discover reads the interfaces like Collect, as no walk is needed.
+func (c *LocalCollector) Discover() (map[int]*IF, error) {
+	return c.Collect(nil)
+}
+
+// Collect reads the interfaces of this machine. Interfaces created or
+// removed since the last read, like veth of containers, are followed.
+func (c *LocalCollector) Collect(ifs map[int]*IF) (map[int]*IF, error) {
+	f, err := os.Open(c.path("proc/net/dev"))
+	if err != nil {
+		return ifs, err
+	}
+	defer f.Close()
+	stats, err := parseNetDev(f)
+	if err != nil {
+		return ifs, err
+	}
+
+	t := time.Now()
+	current := make(map[int]*IF, len(stats))
+	for _, st := range stats {
+		index, err := strconv.Atoi(c.sysfs(st.name, "ifindex"))
+		if err != nil {
+			c.log.Debug().Msgf("no ifindex of %v: %v", st.name, err)
+			continue
+		}
+		i, ok := ifs[index]
+		if !ok {
+			i = NewIF(index, c.log)
+		}
+		i.Desc = st.name
+		i.Name = st.name
+		i.Alias = c.sysfs(st.name, "ifalias")
+		// speed is in Mbps, and unreadable or -1 without a link
+		if speed, err := strconv.ParseInt(c.sysfs(st.name, "speed"), 10, 64); err == nil && speed > 0 {
+			i.Speed = speed * 1000 * 1000
+		} else {
+			i.Speed = 0
+		}
+		i.OperStatus = operState(c.sysfs(st.name, "operstate"))
+		// IFF_UP of the interface flags
+		if flags, err := strconv.ParseInt(strings.TrimPrefix(c.sysfs(st.name, "flags"), "0x"), 16, 64); err == nil {
+			i.AdminStatus = "Down"
+			if flags&1 != 0 {
+				i.AdminStatus = "UP"
+			}
+		}
+
+		i.InOctets.Update(st.rxBytes, t)
+		i.OutOctets.Update(st.txBytes, t)
+		i.InUcastPkts.Update(st.rxPackets-st.rxMcast, t)
+		i.InMcastPkts.Update(st.rxMcast, t)
+		i.OutUcastPkts.Update(st.txPackets, t)
+		i.InError.Update(st.rxErrs, t)
+		i.OutError.Update(st.txErrs, t)
+		i.InDiscards.Update(st.rxDrop, t)
+		i.OutDiscards.Update(st.txDrop, t)
+		current[index] = i
+	}
+	c.health = c.localHealth()
+	return current, nil
+}
+
+func (c *LocalCollector) Health() *Health {
+	return c.health
+}
+
+// operState maps /sys/class/net/<if>/operstate to ifOperStatus. The
+// loopback and some virtual interfaces are "unknown" while they work.
+func operState(s string) string {
+	switch s {
+	case "up", "unknown":
+		return "UP"
+	case "down", "lowerlayerdown", "notpresent":
+		return "Down"
+	}
+	return s
+}
+
+// localHealth reads the hostname, uptime and memory usage. CPU usage needs
+// two samples of /proc/stat and is left unknown.
+func (c *LocalCollector) localHealth() *Health {
+	hl := &Health{CPU: math.NaN(), Memory: math.NaN()}
+	hl.SysName, _ = os.Hostname()
+	hl.SysDescr = "Linux"
+	if b, err := os.ReadFile(c.path("proc/sys/kernel/osrelease")); err == nil {
+		hl.SysDescr = "Linux " + strings.TrimSpace(string(b))
+	}
+	if b, err := os.ReadFile(c.path("proc/uptime")); err == nil {
+		if f := strings.Fields(string(b)); len(f) > 0 {
+			if sec, err := strconv.ParseFloat(f[0], 64); err == nil {
+				hl.UpTime = time.Duration(sec * float64(time.Second))
+			}
+		}
+	}
+	if f, err := os.Open(c.path("proc/meminfo")); err == nil {
+		defer f.Close()
+		mem := make(map[string]float64)
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if f := strings.Fields(s.Text()); len(f) >= 2 {
+				v, _ := strconv.ParseFloat(f[1], 64)
+				mem[strings.TrimSuffix(f[0], ":")] = v
+			}
+		}
+		if total := mem["MemTotal"]; total > 0 {
+			hl.Memory = (total - mem["MemAvailable"]) * 100 / total
+		}
+	}
+	return hl
+}
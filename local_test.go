@@ -0,0 +1,103 @@
+package trmon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testNetDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+	testNetDevLo   = "    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0\n"
+	testNetDevEth0 = "  eth0: 5000000    4000    2    1    0     0          0       100  3000000    3000    0    4    0     0       0          0\n"
+)
+
+func writeLocalRoot(t *testing.T, netDev string, sysfs map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{"proc/net/dev": netDev}
+	for k, v := range sysfs {
+		files[filepath.Join("sys/class/net", k)] = v
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestHost_updateLocal(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	root := writeLocalRoot(t, testNetDevHeader+testNetDevLo+testNetDevEth0, map[string]string{
+		"lo/ifindex":     "1\n",
+		"lo/operstate":   "unknown\n",
+		"lo/flags":       "0x9\n",
+		"eth0/ifindex":   "2\n",
+		"eth0/operstate": "up\n",
+		"eth0/flags":     "0x1003\n",
+		"eth0/speed":     "1000\n",
+		"eth0/ifalias":   "uplink\n",
+	})
+	h := newHost("localhost:local", "", l)
+	c, ok := h.collector.(*LocalCollector)
+	if !ok {
+		t.Fatalf("newHost() of localhost:local has collector %T", h.collector)
+	}
+	c.Root = root
+	if err := h.discover(); err != nil {
+		t.Fatalf("Host.discover() error = %v", err)
+	}
+	h.apply()
+	if !h.Reachable || len(h.IFs) != 2 {
+		t.Fatalf("Host.discover() Reachable = %v, IFs = %v", h.Reachable, h.IFs)
+	}
+
+	lo, eth0 := h.IFs[1], h.IFs[2]
+	if lo.Desc != "lo" || lo.OperStatus != "UP" || lo.AdminStatus != "UP" || lo.Speed != 0 {
+		t.Errorf("lo = %+v", lo)
+	}
+	if eth0.Desc != "eth0" || eth0.Alias != "uplink" || eth0.OperStatus != "UP" || eth0.Speed != 1000*1000*1000 {
+		t.Errorf("eth0 = %+v", eth0)
+	}
+	for _, c := range []struct {
+		counter *Counter
+		want    int64
+	}{
+		{eth0.InOctets, 5000000},
+		{eth0.InUcastPkts, 3900},
+		{eth0.InMcastPkts, 100},
+		{eth0.InError, 2},
+		{eth0.InDiscards, 1},
+		{eth0.OutOctets, 3000000},
+		{eth0.OutUcastPkts, 3000},
+		{eth0.OutDiscards, 4},
+	} {
+		if c.counter.Last != c.want {
+			t.Errorf("%v = %v, want %v", c.counter.name, c.counter.Last, c.want)
+		}
+	}
+
+	// eth0 goes down and lo disappears
+	c.Root = writeLocalRoot(t, testNetDevHeader+testNetDevEth0, map[string]string{
+		"eth0/ifindex":   "2\n",
+		"eth0/operstate": "down\n",
+		"eth0/flags":     "0x1002\n",
+	})
+	kept := h.ifs[2]
+	h.Update()
+	h.apply()
+	if len(h.IFs) != 1 || h.ifs[2] != kept {
+		t.Fatalf("IFs = %v, want eth0 kept", h.IFs)
+	}
+	eth0 = h.IFs[2]
+	if eth0.OperStatus != "Down" || eth0.AdminStatus != "Down" {
+		t.Errorf("eth0 status = %v/%v, want Down/Down", eth0.AdminStatus, eth0.OperStatus)
+	}
+}
@@ -0,0 +1,63 @@
+package trmon
+
+import "fmt"
+
+// Labels chosen for the Name and I/F columns.
+const (
+	hostLabelTarget  = "target"
+	hostLabelSysName = "sysname"
+	ifLabelDescr     = "descr"
+	ifLabelName      = "name"
+)
+
+// setHostLabel chooses the agent target or its sysName for the Name column.
+func (m *MainWidget) setHostLabel(label string) error {
+	switch label {
+	case hostLabelTarget, hostLabelSysName:
+		m.useSysName = label == hostLabelSysName
+		return nil
+	}
+	return fmt.Errorf("Unknown host label %v", label)
+}
+
+// setIFLabel chooses ifDescr or ifName for the I/F column.
+func (m *MainWidget) setIFLabel(label string) error {
+	switch label {
+	case ifLabelDescr, ifLabelName:
+		m.useIfName = label == ifLabelName
+		return nil
+	}
+	return fmt.Errorf("Unknown I/F label %v", label)
+}
+
+func (m *MainWidget) hostLabel() string {
+	if m.useSysName {
+		return hostLabelSysName
+	}
+	return hostLabelTarget
+}
+
+func (m *MainWidget) ifLabel() string {
+	if m.useIfName {
+		return ifLabelName
+	}
+	return ifLabelDescr
+}
+
+// hostName is the name of the host in the Name column. It falls back to
+// the target until sysName is known.
+func (m *MainWidget) hostName(h *Host) string {
+	if m.useSysName && h.Health != nil && h.Health.SysName != "" {
+		return h.Health.SysName
+	}
+	return h.Name
+}
+
+// ifName is the name of the interface in the I/F column. It falls back to
+// ifDescr on agents without ifName.
+func (m *MainWidget) ifName(i *IF) string {
+	if m.useIfName && i.Name != "" {
+		return i.Name
+	}
+	return i.Desc
+}
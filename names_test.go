@@ -0,0 +1,76 @@
+package trmon
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMainWidget_labels(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := testHost("192.0.2.1", l)
+	h.IFs[1].Desc = "GigabitEthernet0/0/1"
+	h.IFs[1].Name = "Gi0/0/1"
+	h.IFs[2].Desc = "GigabitEthernet0/0/2"
+	tests := []struct {
+		name      string
+		hostLabel string
+		ifLabel   string
+		health    *Health
+		index     int
+		wantHost  string
+		wantIF    string
+	}{
+		{
+			name:      "target and ifDescr",
+			hostLabel: "target",
+			ifLabel:   "descr",
+			health:    &Health{SysName: "core1"},
+			index:     1,
+			wantHost:  "192.0.2.1",
+			wantIF:    "GigabitEthernet0/0/1",
+		},
+		{
+			name:      "sysName and ifName",
+			hostLabel: "sysname",
+			ifLabel:   "name",
+			health:    &Health{SysName: "core1"},
+			index:     1,
+			wantHost:  "core1",
+			wantIF:    "Gi0/0/1",
+		},
+		{
+			name:      "fall back before sysName and without ifName",
+			hostLabel: "sysname",
+			ifLabel:   "name",
+			health:    nil,
+			index:     2,
+			wantHost:  "192.0.2.1",
+			wantIF:    "GigabitEthernet0/0/2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMainWidget("main", []*Host{h}, NewNarrowWidget("filter", "", l), l)
+			h.Health = tt.health
+			if err := m.setHostLabel(tt.hostLabel); err != nil {
+				t.Fatalf("MainWidget.setHostLabel() error = %v", err)
+			}
+			if err := m.setIFLabel(tt.ifLabel); err != nil {
+				t.Fatalf("MainWidget.setIFLabel() error = %v", err)
+			}
+			row := m.formatRow(ifRow{h, h.IFs[tt.index]})
+			if row[0] != tt.wantHost || row[1] != tt.wantIF {
+				t.Errorf("MainWidget.formatRow() = %v, want %v %v", strings.Join(row[:2], " "), tt.wantHost, tt.wantIF)
+			}
+		})
+	}
+
+	m := NewMainWidget("main", []*Host{h}, NewNarrowWidget("filter", "", l), l)
+	if err := m.setHostLabel("ip"); err == nil {
+		t.Errorf("MainWidget.setHostLabel() accepts an unknown label")
+	}
+	if err := m.setIFLabel("alias"); err == nil {
+		t.Errorf("MainWidget.setIFLabel() accepts an unknown label")
+	}
+}
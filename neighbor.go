@@ -0,0 +1,239 @@
+package trmon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// LLDP-MIB lldpLocPortTable, indexed by lldpLocPortNum
+	lldpLocPortId   string = ".1.0.8802.1.1.2.1.3.7.1.3"
+	lldpLocPortDesc string = ".1.0.8802.1.1.2.1.3.7.1.4"
+
+	// LLDP-MIB lldpRemTable, indexed by lldpRemTimeMark.lldpRemLocalPortNum.lldpRemIndex
+	lldpRemPortId  string = ".1.0.8802.1.1.2.1.4.1.1.7"
+	lldpRemSysName string = ".1.0.8802.1.1.2.1.4.1.1.9"
+
+	// CISCO-CDP-MIB cdpCacheTable, indexed by cdpCacheIfIndex.cdpCacheDeviceIndex
+	cdpCacheDeviceId   string = ".1.3.6.1.4.1.9.9.23.1.2.1.1.6"
+	cdpCacheDevicePort string = ".1.3.6.1.4.1.9.9.23.1.2.1.1.7"
+)
+
+// neighborInterval is how often neighbor tables are walked. Neighbors
+// rarely change, so they are not walked on every poll.
+const neighborInterval = 5 * time.Minute
+
+// Neighbor is a device found on the other end of an interface.
+type Neighbor struct {
+	Protocol string
+	SysName  string
+	PortID   string
+}
+
+func (n Neighbor) String() string {
+	if n.PortID == "" {
+		return n.SysName
+	}
+	return fmt.Sprintf("%v %v", n.SysName, n.PortID)
+}
+
+// neighbor returns the neighbors of the interface in a table cell.
+func (i *IF) neighbor() string {
+	s := make([]string, 0, len(i.Neighbors))
+	for _, n := range i.Neighbors {
+		s = append(s, n.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+// updateNeighbors walks LLDP-MIB, and CISCO-CDP-MIB when enabled, once
+// neighborInterval has passed since the last walk.
+func (c *SNMPCollector) updateNeighbors() {
+	if time.Since(c.neighborsAt) < neighborInterval {
+		return
+	}
+	c.neighborsAt = time.Now()
+
+	ports := make(lldpLocPorts)
+	for _, oid := range []string{lldpLocPortId, lldpLocPortDesc} {
+		if err := c.bulkWalk(oid, ports.value); err != nil {
+			c.log.Debug().Msgf("Failed to Update %v: %v", oid, err)
+		}
+	}
+	t := make(neighborTable)
+	for _, oid := range []string{lldpRemSysName, lldpRemPortId} {
+		if err := c.bulkWalk(oid, t.lldpValue); err != nil {
+			c.log.Debug().Msgf("Failed to Update %v: %v", oid, err)
+		}
+	}
+	if c.CDP {
+		for _, oid := range []string{cdpCacheDeviceId, cdpCacheDevicePort} {
+			if err := c.bulkWalk(oid, t.cdpValue); err != nil {
+				c.log.Debug().Msgf("Failed to Update %v: %v", oid, err)
+			}
+		}
+	}
+	t.assign(c.ifs, ports.ifIndexes(c.ifs))
+}
+
+// lldpLocPorts are the names of local ports by lldpLocPortNum, from
+// lldpLocPortId and lldpLocPortDesc in this order.
+type lldpLocPorts map[int][]string
+
+func (p lldpLocPorts) value(pdu gosnmp.SnmpPDU) error {
+	for _, column := range []string{lldpLocPortId, lldpLocPortDesc} {
+		if !strings.HasPrefix(pdu.Name, column+".") {
+			continue
+		}
+		num, err := strconv.Atoi(strings.TrimPrefix(pdu.Name, column+"."))
+		if err != nil {
+			return nil
+		}
+		if s := octetString(pdu.Value); s != "" {
+			p[num] = append(p[num], s)
+		}
+	}
+	return nil
+}
+
+// ifIndexes maps lldpLocPortNum to the ifIndex of the interface whose
+// ifName or ifDescr is a name of the port, the lowest one if several are.
+func (p lldpLocPorts) ifIndexes(ifs map[int]*IF) map[int]int {
+	indexes := make(map[int]int, len(p))
+	for num, names := range p {
+		for _, name := range names {
+			found := 0
+			for index, i := range ifs {
+				if (name == i.Name || name == i.Desc) && (found == 0 || index < found) {
+					found = index
+				}
+			}
+			if found != 0 {
+				indexes[num] = found
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+type neighborKey struct {
+	protocol string
+	index    int
+	remote   string
+}
+
+// neighborTable collects the columns of remote entries during a walk.
+type neighborTable map[neighborKey]*Neighbor
+
+func (t neighborTable) entry(protocol string, index int, remote string) *Neighbor {
+	k := neighborKey{protocol, index, remote}
+	n, ok := t[k]
+	if !ok {
+		n = &Neighbor{Protocol: protocol}
+		t[k] = n
+	}
+	return n
+}
+
+// lldpValue sets lldpRemSysName or lldpRemPortId by lldpRemLocalPortNum.
+func (t neighborTable) lldpValue(pdu gosnmp.SnmpPDU) error {
+	for _, column := range []string{lldpRemSysName, lldpRemPortId} {
+		if !strings.HasPrefix(pdu.Name, column+".") {
+			continue
+		}
+		s := strings.Split(strings.TrimPrefix(pdu.Name, column+"."), ".")
+		if len(s) != 3 {
+			return nil
+		}
+		index, err := strconv.Atoi(s[1])
+		if err != nil {
+			return nil
+		}
+		n := t.entry("LLDP", index, s[2])
+		if column == lldpRemSysName {
+			n.SysName = octetString(pdu.Value)
+		} else {
+			n.PortID = octetString(pdu.Value)
+		}
+	}
+	return nil
+}
+
+// cdpValue sets cdpCacheDeviceId or cdpCacheDevicePort.
+func (t neighborTable) cdpValue(pdu gosnmp.SnmpPDU) error {
+	for _, column := range []string{cdpCacheDeviceId, cdpCacheDevicePort} {
+		if !strings.HasPrefix(pdu.Name, column+".") {
+			continue
+		}
+		s := strings.Split(strings.TrimPrefix(pdu.Name, column+"."), ".")
+		if len(s) != 2 {
+			return nil
+		}
+		index, err := strconv.Atoi(s[0])
+		if err != nil {
+			return nil
+		}
+		n := t.entry("CDP", index, s[1])
+		if column == cdpCacheDeviceId {
+			n.SysName = octetString(pdu.Value)
+		} else {
+			n.PortID = octetString(pdu.Value)
+		}
+	}
+	return nil
+}
+
+// assign replaces the neighbors of every interface in ifs, so neighbors
+// which disappeared are forgotten. LLDP entries are put on the ifIndex of
+// their lldpRemLocalPortNum in ports, or on the same ifIndex, which is what
+// most agents use, when the local port is not found.
+func (t neighborTable) assign(ifs map[int]*IF, ports map[int]int) {
+	keys := make([]neighborKey, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	// LLDP first, then in the order of the remote entries
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].protocol != keys[j].protocol {
+			return keys[i].protocol > keys[j].protocol
+		}
+		return keys[i].remote < keys[j].remote
+	})
+	neighbors := make(map[int][]Neighbor)
+	for _, k := range keys {
+		index := k.index
+		if i, ok := ports[k.index]; ok && k.protocol == "LLDP" {
+			index = i
+		}
+		neighbors[index] = append(neighbors[index], *t[k])
+	}
+	for index, i := range ifs {
+		i.Neighbors = neighbors[index]
+	}
+}
+
+// octetString returns a printable OCTET STRING as is and any other one,
+// such as a MAC address, in hex.
+func octetString(v interface{}) string {
+	b, ok := v.([]byte)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	for _, c := range string(b) {
+		if !unicode.IsPrint(c) {
+			s := make([]string, len(b))
+			for i := range b {
+				s[i] = fmt.Sprintf("%02x", b[i])
+			}
+			return strings.Join(s, ":")
+		}
+	}
+	return string(b)
+}
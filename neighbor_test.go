@@ -0,0 +1,99 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestNeighborTable_assign(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	tests := []struct {
+		name  string
+		ports []gosnmp.SnmpPDU
+		lldp  []gosnmp.SnmpPDU
+		cdp   []gosnmp.SnmpPDU
+		want  map[int][]Neighbor
+	}{
+		{
+			name: "lldp",
+			lldp: []gosnmp.SnmpPDU{
+				{Name: lldpRemSysName + ".0.1.3", Value: []byte("core2")},
+				{Name: lldpRemSysName + ".0.2.4", Value: []byte("edge1")},
+				{Name: lldpRemPortId + ".0.1.3", Value: []byte("ge-0/0/1")},
+				{Name: lldpRemPortId + ".0.2.4", Value: []byte{0x00, 0x1b, 0x21, 0x0a, 0x0b, 0x0c}},
+			},
+			want: map[int][]Neighbor{
+				1: {{Protocol: "LLDP", SysName: "core2", PortID: "ge-0/0/1"}},
+				2: {{Protocol: "LLDP", SysName: "edge1", PortID: "00:1b:21:0a:0b:0c"}},
+				3: nil,
+			},
+		},
+		{
+			name: "lldp local ports by name",
+			ports: []gosnmp.SnmpPDU{
+				// a MAC address as lldpLocPortId, then ifDescr as lldpLocPortDesc
+				{Name: lldpLocPortId + ".11", Value: []byte{0x00, 0x1b, 0x21, 0x0a, 0x0b, 0x0d}},
+				{Name: lldpLocPortDesc + ".11", Value: []byte("eth2")},
+				{Name: lldpLocPortId + ".12", Value: []byte("eth0")},
+			},
+			lldp: []gosnmp.SnmpPDU{
+				{Name: lldpRemSysName + ".0.11.1", Value: []byte("core2")},
+				{Name: lldpRemSysName + ".0.12.1", Value: []byte("edge1")},
+				// not in lldpLocPortTable, taken as ifIndex
+				{Name: lldpRemSysName + ".0.2.1", Value: []byte("edge2")},
+			},
+			want: map[int][]Neighbor{
+				1: {{Protocol: "LLDP", SysName: "edge1"}},
+				2: {{Protocol: "LLDP", SysName: "edge2"}},
+				3: {{Protocol: "LLDP", SysName: "core2"}},
+			},
+		},
+		{
+			name: "lldp and cdp on the same port",
+			lldp: []gosnmp.SnmpPDU{
+				{Name: lldpRemSysName + ".0.3.1", Value: []byte("core2")},
+			},
+			cdp: []gosnmp.SnmpPDU{
+				{Name: cdpCacheDeviceId + ".3.7", Value: []byte("core2.example.com")},
+				{Name: cdpCacheDevicePort + ".3.7", Value: []byte("Gi0/1")},
+			},
+			want: map[int][]Neighbor{
+				1: nil,
+				2: nil,
+				3: {
+					{Protocol: "LLDP", SysName: "core2"},
+					{Protocol: "CDP", SysName: "core2.example.com", PortID: "Gi0/1"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHost("core1", l)
+			// Neighbors not found by the walk are forgotten
+			h.IFs[1].Neighbors = []Neighbor{{Protocol: "LLDP", SysName: "old"}}
+			ports := make(lldpLocPorts)
+			for _, pdu := range tt.ports {
+				ports.value(pdu)
+			}
+			nt := make(neighborTable)
+			for _, pdu := range tt.lldp {
+				nt.lldpValue(pdu)
+			}
+			for _, pdu := range tt.cdp {
+				nt.cdpValue(pdu)
+			}
+			nt.assign(h.IFs, ports.ifIndexes(h.IFs))
+			got := make(map[int][]Neighbor)
+			for index, i := range h.IFs {
+				got[index] = i.Neighbors
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Neighbors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
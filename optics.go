@@ -0,0 +1,352 @@
+package trmon
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// ENTITY-MIB
+	entPhysicalContainedIn    string = ".1.3.6.1.2.1.47.1.1.1.1.4"
+	entPhysicalName           string = ".1.3.6.1.2.1.47.1.1.1.1.7"
+	entAliasMappingIdentifier string = ".1.3.6.1.2.1.47.1.3.2.1.2"
+
+	// ENTITY-SENSOR-MIB entPhySensorTable, indexed by entPhysicalIndex
+	entPhySensorEntry     string = ".1.3.6.1.2.1.99.1.1.1"
+	entPhySensorType      string = ".1.3.6.1.2.1.99.1.1.1.1."
+	entPhySensorScale     string = ".1.3.6.1.2.1.99.1.1.1.2."
+	entPhySensorPrecision string = ".1.3.6.1.2.1.99.1.1.1.3."
+	entPhySensorValue     string = ".1.3.6.1.2.1.99.1.1.1.4."
+)
+
+// EntitySensorDataType values of the sensors found on transceivers
+const (
+	sensorAmperes = 5
+	sensorWatts   = 6
+	sensorCelsius = 8
+	sensorDBm     = 14
+)
+
+// opticsInterval is how often transceiver readings are walked.
+const opticsInterval = time.Minute
+
+// Optics is the DOM reading of the transceiver of an interface. A value
+// the agent does not report is NaN. A multi-lane transceiver reports its
+// weakest lane.
+type Optics struct {
+	// RxPower and TxPower are in dBm
+	RxPower float64
+	TxPower float64
+	// Temperature is in celsius
+	Temperature float64
+	// Bias is the laser bias current in mA
+	Bias float64
+}
+
+func newOptics() *Optics {
+	return &Optics{
+		RxPower:     math.NaN(),
+		TxPower:     math.NaN(),
+		Temperature: math.NaN(),
+		Bias:        math.NaN(),
+	}
+}
+
+// lower keeps the lowest value, since the weakest lane matters.
+func lower(v *float64, x float64) {
+	if math.IsNaN(*v) || x < *v {
+		*v = x
+	}
+}
+
+// higher keeps the highest value.
+func higher(v *float64, x float64) {
+	if math.IsNaN(*v) || x > *v {
+		*v = x
+	}
+}
+
+// OpticThresholds are the levels highlighted in the table and the detail view.
+type OpticThresholds struct {
+	RxLow    float64 `json:"rx_low"`
+	TxLow    float64 `json:"tx_low"`
+	TempHigh float64 `json:"temp_high"`
+}
+
+// DefaultOpticThresholds suits common 10G LR optics.
+var DefaultOpticThresholds = OpticThresholds{
+	RxLow:    -14,
+	TxLow:    -8,
+	TempHigh: 70,
+}
+
+func (t OpticThresholds) lowRx(o *Optics) bool {
+	return o != nil && o.RxPower < t.RxLow
+}
+
+func (t OpticThresholds) lowTx(o *Optics) bool {
+	return o != nil && o.TxPower < t.TxLow
+}
+
+func (t OpticThresholds) hot(o *Optics) bool {
+	return o != nil && o.Temperature > t.TempHigh
+}
+
+// lowLight reports whether either direction is below its threshold.
+func (t OpticThresholds) lowLight(o *Optics) bool {
+	return t.lowRx(o) || t.lowTx(o)
+}
+
+// DOMProfile maps vendor DOM table columns indexed by ifIndex, for agents
+// without ENTITY-SENSOR-MIB.
+//
+//	{"name": "juniper", "rx_power": {"oid": ".1.3.6.1.4.1.2636.3.60.1.1.1.1.5", "scale": 0.01}}
+type DOMProfile struct {
+	Name        string     `json:"name"`
+	RxPower     *DOMColumn `json:"rx_power"`
+	TxPower     *DOMColumn `json:"tx_power"`
+	Temperature *DOMColumn `json:"temperature"`
+	Bias        *DOMColumn `json:"bias"`
+}
+
+// DOMColumn is a column whose value multiplied by Scale is in dBm, celsius
+// or mA. Scale 0 means 1.
+type DOMColumn struct {
+	OID   string  `json:"oid"`
+	Scale float64 `json:"scale"`
+}
+
+func (c *DOMColumn) value(pdu gosnmp.SnmpPDU) float64 {
+	v := float64(gosnmp.ToBigInt(pdu.Value).Int64())
+	if c.Scale == 0 {
+		return v
+	}
+	return v * c.Scale
+}
+
+// updateOptics walks the DOM profile of the host, or ENTITY-SENSOR-MIB
+// without a profile, once opticsInterval has passed since the last walk.
+func (c *SNMPCollector) updateOptics() {
+	if time.Since(c.opticsAt) < opticsInterval {
+		return
+	}
+	c.opticsAt = time.Now()
+
+	var optics map[int]*Optics
+	if c.DOMProfile != nil {
+		optics = c.walkDOMProfile()
+	} else {
+		optics = c.walkEntitySensors()
+	}
+	for index, i := range c.ifs {
+		i.Optics = optics[index]
+	}
+}
+
+func (c *SNMPCollector) walkDOMProfile() map[int]*Optics {
+	optics := make(map[int]*Optics)
+	p := c.DOMProfile
+	for _, d := range []struct {
+		column *DOMColumn
+		set    func(o *Optics, v float64)
+	}{
+		{p.RxPower, func(o *Optics, v float64) { lower(&o.RxPower, v) }},
+		{p.TxPower, func(o *Optics, v float64) { lower(&o.TxPower, v) }},
+		{p.Temperature, func(o *Optics, v float64) { higher(&o.Temperature, v) }},
+		{p.Bias, func(o *Optics, v float64) { higher(&o.Bias, v) }},
+	} {
+		if d.column == nil {
+			continue
+		}
+		column, set := d.column, d.set
+		err := c.bulkWalk(column.OID, func(pdu gosnmp.SnmpPDU) error {
+			index, ok := lastIndex(pdu.Name)
+			if !ok {
+				return nil
+			}
+			o, ok := optics[index]
+			if !ok {
+				o = newOptics()
+				optics[index] = o
+			}
+			set(o, column.value(pdu))
+			return nil
+		})
+		if err != nil {
+			c.log.Debug().Msgf("Failed to Update %v: %v", column.OID, err)
+		}
+	}
+	return optics
+}
+
+func (c *SNMPCollector) walkEntitySensors() map[int]*Optics {
+	t := newEntityTable()
+	for _, w := range []struct {
+		oid string
+		fn  gosnmp.WalkFunc
+	}{
+		{entAliasMappingIdentifier, t.aliasValue},
+		{entPhysicalContainedIn, t.containedInValue},
+		{entPhysicalName, t.nameValue},
+		{entPhySensorEntry, t.sensorValue},
+	} {
+		if err := c.bulkWalk(w.oid, w.fn); err != nil {
+			c.log.Debug().Msgf("Failed to Update %v: %v", w.oid, err)
+		}
+	}
+	return t.optics()
+}
+
+func lastIndex(oid string) (int, bool) {
+	s := strings.Split(oid, ".")
+	index, err := strconv.Atoi(s[len(s)-1])
+	return index, err == nil
+}
+
+// firstIndex returns the first sub-identifier following column.
+func firstIndex(oid string, column string) (int, bool) {
+	s := strings.Split(strings.TrimPrefix(oid, column), ".")
+	index, err := strconv.Atoi(s[0])
+	return index, err == nil
+}
+
+type entitySensor struct {
+	kind      int
+	scale     int
+	precision int
+	value     int64
+}
+
+// reading returns the sensor value in its unit.
+func (s *entitySensor) reading() float64 {
+	// EntitySensorDataScale 9 is units and each step is 10^3
+	return float64(s.value) * math.Pow10(3*(s.scale-9)-s.precision)
+}
+
+// entityTable collects the entities needed to find the interface of each
+// sensor. A transceiver sensor is usually contained in the port entity
+// which is mapped to ifIndex by entAliasMappingTable.
+type entityTable struct {
+	ifIndex map[int]int
+	parent  map[int]int
+	name    map[int]string
+	sensors map[int]*entitySensor
+}
+
+func newEntityTable() *entityTable {
+	return &entityTable{
+		ifIndex: make(map[int]int),
+		parent:  make(map[int]int),
+		name:    make(map[int]string),
+		sensors: make(map[int]*entitySensor),
+	}
+}
+
+func (t *entityTable) aliasValue(pdu gosnmp.SnmpPDU) error {
+	entity, ok := firstIndex(pdu.Name, entAliasMappingIdentifier+".")
+	if !ok {
+		return nil
+	}
+	// The value is the ifIndex instance like .1.3.6.1.2.1.2.2.1.1.<ifIndex>
+	oid, ok := pdu.Value.(string)
+	if !ok || !strings.HasPrefix(oid, ifIndex+".") {
+		return nil
+	}
+	if index, ok := lastIndex(oid); ok {
+		t.ifIndex[entity] = index
+	}
+	return nil
+}
+
+func (t *entityTable) containedInValue(pdu gosnmp.SnmpPDU) error {
+	if entity, ok := lastIndex(pdu.Name); ok {
+		t.parent[entity] = int(gosnmp.ToBigInt(pdu.Value).Int64())
+	}
+	return nil
+}
+
+func (t *entityTable) nameValue(pdu gosnmp.SnmpPDU) error {
+	if entity, ok := lastIndex(pdu.Name); ok {
+		t.name[entity] = strings.ToLower(octetString(pdu.Value))
+	}
+	return nil
+}
+
+func (t *entityTable) sensorValue(pdu gosnmp.SnmpPDU) error {
+	entity, ok := lastIndex(pdu.Name)
+	if !ok {
+		return nil
+	}
+	s, ok := t.sensors[entity]
+	if !ok {
+		s = &entitySensor{scale: 9}
+		t.sensors[entity] = s
+	}
+	v := int(gosnmp.ToBigInt(pdu.Value).Int64())
+	switch {
+	case strings.HasPrefix(pdu.Name, entPhySensorType):
+		s.kind = v
+	case strings.HasPrefix(pdu.Name, entPhySensorScale):
+		s.scale = v
+	case strings.HasPrefix(pdu.Name, entPhySensorPrecision):
+		s.precision = v
+	case strings.HasPrefix(pdu.Name, entPhySensorValue):
+		s.value = int64(v)
+	}
+	return nil
+}
+
+// interfaceOf climbs the containment tree of the entity up to its port.
+func (t *entityTable) interfaceOf(entity int) (int, bool) {
+	for depth := 0; depth < 8 && entity != 0; depth++ {
+		if index, ok := t.ifIndex[entity]; ok {
+			return index, true
+		}
+		entity = t.parent[entity]
+	}
+	return 0, false
+}
+
+// optics returns the readings by ifIndex. Power sensors are told apart by
+// their entPhysicalName such as "Te1/1 Receive Power Sensor".
+func (t *entityTable) optics() map[int]*Optics {
+	optics := make(map[int]*Optics)
+	for entity, s := range t.sensors {
+		index, ok := t.interfaceOf(entity)
+		if !ok {
+			continue
+		}
+		o, ok := optics[index]
+		if !ok {
+			o = newOptics()
+		}
+		v := s.reading()
+		name := t.name[entity]
+		switch s.kind {
+		case sensorCelsius:
+			higher(&o.Temperature, v)
+		case sensorAmperes:
+			higher(&o.Bias, v*1000)
+		case sensorWatts, sensorDBm:
+			if s.kind == sensorWatts {
+				v = 10 * math.Log10(v*1000)
+			}
+			switch {
+			case strings.Contains(name, "rx") || strings.Contains(name, "receive"):
+				lower(&o.RxPower, v)
+			case strings.Contains(name, "tx") || strings.Contains(name, "transmit"):
+				lower(&o.TxPower, v)
+			default:
+				continue
+			}
+		default:
+			continue
+		}
+		optics[index] = o
+	}
+	return optics
+}
@@ -0,0 +1,155 @@
+package trmon
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestEntityTable_optics(t *testing.T) {
+	et := newEntityTable()
+	walks := []struct {
+		fn   gosnmp.WalkFunc
+		pdus []gosnmp.SnmpPDU
+	}{
+		{et.aliasValue, []gosnmp.SnmpPDU{
+			// port entity 1000 is ifIndex 5
+			{Name: entAliasMappingIdentifier + ".1000.0", Type: gosnmp.ObjectIdentifier, Value: ifIndex + ".5"},
+		}},
+		{et.containedInValue, []gosnmp.SnmpPDU{
+			// transceiver 1100 in port 1000, sensors in the transceiver
+			{Name: entPhysicalContainedIn + ".1100", Value: 1000},
+			{Name: entPhysicalContainedIn + ".1101", Value: 1100},
+			{Name: entPhysicalContainedIn + ".1102", Value: 1100},
+			{Name: entPhysicalContainedIn + ".1103", Value: 1100},
+			{Name: entPhysicalContainedIn + ".1104", Value: 1100},
+			{Name: entPhysicalContainedIn + ".1105", Value: 1100},
+			// a sensor of the chassis
+			{Name: entPhysicalContainedIn + ".9", Value: 1},
+		}},
+		{et.nameValue, []gosnmp.SnmpPDU{
+			{Name: entPhysicalName + ".1101", Value: []byte("Te1/1 Receive Power Sensor")},
+			{Name: entPhysicalName + ".1102", Value: []byte("Te1/1 Lane 2 Rx Power")},
+			{Name: entPhysicalName + ".1103", Value: []byte("Te1/1 Transmit Power Sensor")},
+			{Name: entPhysicalName + ".1104", Value: []byte("Te1/1 Temperature Sensor")},
+			{Name: entPhysicalName + ".1105", Value: []byte("Te1/1 Bias Current Sensor")},
+		}},
+		{et.sensorValue, []gosnmp.SnmpPDU{
+			// -5.23 dBm
+			{Name: entPhySensorType + "1101", Value: sensorDBm},
+			{Name: entPhySensorScale + "1101", Value: 9},
+			{Name: entPhySensorPrecision + "1101", Value: 2},
+			{Name: entPhySensorValue + "1101", Value: -523},
+			// 0.1 mW is -10 dBm, the weaker lane
+			{Name: entPhySensorType + "1102", Value: sensorWatts},
+			{Name: entPhySensorScale + "1102", Value: 7},
+			{Name: entPhySensorPrecision + "1102", Value: 0},
+			{Name: entPhySensorValue + "1102", Value: 100},
+			// -2.1 dBm
+			{Name: entPhySensorType + "1103", Value: sensorDBm},
+			{Name: entPhySensorPrecision + "1103", Value: 1},
+			{Name: entPhySensorValue + "1103", Value: -21},
+			// 35.5 C
+			{Name: entPhySensorType + "1104", Value: sensorCelsius},
+			{Name: entPhySensorPrecision + "1104", Value: 1},
+			{Name: entPhySensorValue + "1104", Value: 355},
+			// 6.5 mA
+			{Name: entPhySensorType + "1105", Value: sensorAmperes},
+			{Name: entPhySensorScale + "1105", Value: 7},
+			{Name: entPhySensorPrecision + "1105", Value: 1},
+			{Name: entPhySensorValue + "1105", Value: 6500 * 10},
+			{Name: entPhySensorType + "9", Value: sensorCelsius},
+			{Name: entPhySensorValue + "9", Value: 40},
+		}},
+	}
+	for _, w := range walks {
+		for _, pdu := range w.pdus {
+			if err := w.fn(pdu); err != nil {
+				t.Fatalf("walk %v error = %v", pdu.Name, err)
+			}
+		}
+	}
+
+	optics := et.optics()
+	if len(optics) != 1 {
+		t.Fatalf("entityTable.optics() = %v, want only ifIndex 5", optics)
+	}
+	o := optics[5]
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"RxPower", o.RxPower, -10},
+		{"TxPower", o.TxPower, -2.1},
+		{"Temperature", o.Temperature, 35.5},
+		{"Bias", o.Bias, 6.5},
+	} {
+		if math.Abs(c.got-c.want) > 0.001 {
+			t.Errorf("%v = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestOpticThresholds(t *testing.T) {
+	th := DefaultOpticThresholds
+	tests := []struct {
+		name         string
+		optics       *Optics
+		wantLowLight bool
+		wantHot      bool
+	}{
+		{
+			name:   "no readings",
+			optics: nil,
+		},
+		{
+			name:   "not reported",
+			optics: newOptics(),
+		},
+		{
+			name:   "good",
+			optics: &Optics{RxPower: -3, TxPower: -2, Temperature: 40, Bias: 6},
+		},
+		{
+			name:         "dirty fiber",
+			optics:       &Optics{RxPower: -20, TxPower: -2, Temperature: 40, Bias: 6},
+			wantLowLight: true,
+		},
+		{
+			name:    "hot",
+			optics:  &Optics{RxPower: -3, TxPower: -2, Temperature: 75, Bias: 6},
+			wantHot: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := th.lowLight(tt.optics); got != tt.wantLowLight {
+				t.Errorf("OpticThresholds.lowLight() = %v, want %v", got, tt.wantLowLight)
+			}
+			if got := th.hot(tt.optics); got != tt.wantHot {
+				t.Errorf("OpticThresholds.hot() = %v, want %v", got, tt.wantHot)
+			}
+		})
+	}
+}
+
+func TestDOMColumn_value(t *testing.T) {
+	tests := []struct {
+		name   string
+		column DOMColumn
+		value  interface{}
+		want   float64
+	}{
+		{"scaled", DOMColumn{Scale: 0.01}, -523, -5.23},
+		{"no scale", DOMColumn{}, 35, 35},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.column.value(gosnmp.SnmpPDU{Value: tt.value})
+			if math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("DOMColumn.value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
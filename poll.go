@@ -0,0 +1,66 @@
+package trmon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxPolls is how many agents are polled at once unless configured.
+const DefaultMaxPolls = 16
+
+// poll runs f for h once a polling slot is free, so that no more than
+// cap(a.slots) agents are polled at once, and records how long f took.
+// Without slots any number of polls run at once. It reports false when ctx
+// is done before a slot is free.
+func (a *App) poll(ctx context.Context, h *Host, f func()) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if a.slots != nil {
+		select {
+		case a.slots <- struct{}{}:
+			defer func() { <-a.slots }()
+		case <-ctx.Done():
+			return false
+		}
+	}
+	atomic.AddInt64(&a.polling, 1)
+	defer atomic.AddInt64(&a.polling, -1)
+
+	start := time.Now()
+	f()
+	h.polled(time.Since(start), time.Now())
+	return true
+}
+
+// pollStatus summarizes the polls for the title of the main view.
+func (a *App) pollStatus() string {
+	s := make([]string, 0, 3)
+	if a.slots != nil {
+		s = append(s, fmt.Sprintf("polling %d/%d", atomic.LoadInt64(&a.polling), cap(a.slots)))
+	} else {
+		s = append(s, fmt.Sprintf("polling %d", atomic.LoadInt64(&a.polling)))
+	}
+
+	var slowest *Host
+	var total time.Duration
+	n := 0
+	for _, h := range a.hosts {
+		if h.virtual || h.PolledAt.IsZero() {
+			continue
+		}
+		total += h.PollTime
+		n++
+		if slowest == nil || h.PollTime > slowest.PollTime {
+			slowest = h
+		}
+	}
+	if n > 0 {
+		s = append(s, fmt.Sprintf("mean %v", (total/time.Duration(n)).Round(time.Millisecond)))
+		s = append(s, fmt.Sprintf("slowest %v %v", slowest.Name, slowest.PollTime.Round(time.Millisecond)))
+	}
+	return strings.Join(s, ", ")
+}
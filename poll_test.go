@@ -0,0 +1,102 @@
+package trmon
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestApp_poll(t *testing.T) {
+	l := NewLogger(false, os.Stdout)
+	a := &App{log: l, slots: make(chan struct{}, 2)}
+	var running, most int64
+	var wg sync.WaitGroup
+	hosts := make([]*Host, 8)
+	for n := range hosts {
+		hosts[n] = newHost("core1", "", l)
+		wg.Add(1)
+		go func(h *Host) {
+			defer wg.Done()
+			a.poll(context.Background(), h, func() {
+				r := atomic.AddInt64(&running, 1)
+				for {
+					m := atomic.LoadInt64(&most)
+					if r <= m || atomic.CompareAndSwapInt64(&most, m, r) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt64(&running, -1)
+			})
+		}(hosts[n])
+	}
+	wg.Wait()
+	if most != 2 {
+		t.Errorf("%v polls ran at once, want 2", most)
+	}
+	for _, h := range hosts {
+		h.apply()
+		if h.PollTime < 10*time.Millisecond || h.PolledAt.IsZero() {
+			t.Errorf("PollTime = %v, PolledAt = %v", h.PollTime, h.PolledAt)
+		}
+	}
+
+	// A poll waiting for a slot gives up when its context is done
+	a.slots <- struct{}{}
+	a.slots <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if a.poll(ctx, hosts[0], func() { t.Errorf("polled without a slot") }) {
+		t.Errorf("App.poll() = true, want false")
+	}
+}
+
+func TestApp_pollStatus(t *testing.T) {
+	l := NewLogger(false, os.Stdout)
+	core, edge := newHost("core1", "", l), newHost("edge1", "", l)
+	a := &App{log: l, slots: make(chan struct{}, 16), hosts: []*Host{core, edge, newHost("down1", "", l)}}
+	core.PollTime, core.PolledAt = 100*time.Millisecond, time.Now()
+	edge.PollTime, edge.PolledAt = 2300*time.Millisecond, time.Now()
+	if got, want := a.pollStatus(), "polling 0/16, mean 1.2s, slowest edge1 2.3s"; got != want {
+		t.Errorf("App.pollStatus() = %q, want %q", got, want)
+	}
+}
+
+// TestApp_poll_status polls while the UI reads the host, for go test -race.
+func TestApp_poll_status(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
+	l := NewLogger(false, os.Stdout)
+	h, err := NewHost(agent.Target, "my_comm", l)
+	if err != nil {
+		t.Fatalf("NewHost() error = %v", err)
+	}
+	defer h.close()
+	a := &App{log: l, hosts: []*Host{h}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 5; n++ {
+			a.poll(context.Background(), h, h.Update)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			h.apply()
+			if h.PolledAt.IsZero() || len(h.IFs) == 0 {
+				t.Errorf("PolledAt = %v, IFs = %v after polls", h.PolledAt, h.IFs)
+			}
+			return
+		default:
+			h.apply()
+			a.pollStatus()
+			for _, i := range h.IFs {
+				_ = i.InOctets.Rate
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
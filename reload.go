@@ -0,0 +1,207 @@
+package trmon
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jroimartin/gocui"
+)
+
+// reload reads the configuration file again and applies what changed since
+// it was last read. Hosts, alerts and aggregates given on the command line
+// or added while running are left alone, and so are hosts of the file whose
+// options did not change, keeping their history. It must run in the main
+// loop like any other change of the hosts.
+func (a *App) reload(g *gocui.Gui) error {
+	if a.configFile == "" {
+		return fmt.Errorf("no configuration file to reload")
+	}
+	fc, err := LoadConfigFile(a.configFile)
+	if err != nil {
+		return err
+	}
+	old := a.fileConfig
+	if err := a.conflicts(old, fc); err != nil {
+		return err
+	}
+	a.fileConfig = fc
+	a.setProfiles(fc)
+	a.log.Info().Msgf("reload config %v", a.configFile)
+
+	// fc is validated by LoadConfigFile and conflicts, so that nothing of
+	// it fails to apply
+	a.reloadHosts(old, fc)
+	a.reloadAggregates(old.Aggregates, fc.Aggregates)
+	a.reloadAlerts(old.Alerts, fc.Alerts)
+	a.mw.thresholds = fc.opticThresholds()
+	switch {
+	case fc.Interval > 0 && fc.Interval != old.Interval:
+		a.setInterval(fc.Interval)
+	case fc.Interval == 0 && old.Interval > 0:
+		// back to -i as if the file never gave one
+		a.setInterval(a.flagInterval)
+	}
+	if fc.Filter != old.Filter {
+		setFilterView(g, a.mw.NarrowWidget, fc.Filter)
+	}
+	return nil
+}
+
+// reloadNotify reloads the configuration and reports the result in the
+// title of the table, for the key and SIGHUP.
+func (a *App) reloadNotify(g *gocui.Gui) {
+	if err := a.reload(g); err != nil {
+		a.log.Warn().Msgf("failed to reload config: %v", err)
+		a.mw.notify(fmt.Sprintf("reload failed: %v", err))
+		return
+	}
+	a.mw.notify("reloaded " + a.configFile)
+}
+
+// conflicts reports hosts, aggregates and alerts fc adds which are already
+// there from the command line or from while running.
+func (a *App) conflicts(old, fc *FileConfig) error {
+	olds := make(map[string]bool, len(old.Hosts))
+	for _, hc := range old.Hosts {
+		olds[hc.Name] = true
+	}
+	for _, hc := range fc.Hosts {
+		if olds[hc.Name] {
+			continue
+		}
+		for _, h := range a.hosts {
+			if h.Name == hc.Name {
+				return fmt.Errorf("host %v is already monitored", hc.Name)
+			}
+		}
+	}
+	for _, ac := range fc.Aggregates {
+		for _, ag := range a.mw.aggregates {
+			if ag.Name == ac.Name && !ag.fromConfig {
+				return fmt.Errorf("aggregate %v already exists", ac.Name)
+			}
+		}
+	}
+	for _, expr := range fc.Alerts {
+		for _, r := range a.mw.alerts {
+			if r.expr == expr && !r.fromConfig {
+				return fmt.Errorf("alert %v already exists", expr)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *App) reloadHosts(old, fc *FileConfig) {
+	olds := make(map[string]HostConfig, len(old.Hosts))
+	for _, hc := range old.Hosts {
+		olds[hc.Name] = hc
+	}
+	news := make(map[string]bool, len(fc.Hosts))
+	for _, hc := range fc.Hosts {
+		news[hc.Name] = true
+		prev, ok := olds[hc.Name]
+		switch {
+		case !ok:
+			a.addHost(hc)
+		case hostConfigChanged(prev, hc, old, fc):
+			if err := a.replaceHost(hc); err != nil {
+				// removed while running, so it is added again
+				a.addHost(hc)
+			}
+		case prev.Group != hc.Group:
+			for _, h := range a.hosts {
+				if h.Name == hc.Name && !h.virtual {
+					h.Group = hc.Group
+				}
+			}
+		}
+	}
+	for _, hc := range old.Hosts {
+		if news[hc.Name] {
+			continue
+		}
+		if err := a.removeHost(hc.Name); err != nil {
+			a.log.Debug().Msgf("skip removing host %v: %v", hc.Name, err)
+		}
+	}
+}
+
+// hostConfigChanged reports whether the agent of hc has to be polled anew
+// as its options, or the profiles it names, differ from those of prev.
+// A new group only moves the host.
+func hostConfigChanged(prev, hc HostConfig, old, fc *FileConfig) bool {
+	prev.Group, hc.Group = "", ""
+	// Retries is a pointer, so the values are compared
+	if !reflect.DeepEqual(prev, hc) {
+		return true
+	}
+	return !reflect.DeepEqual(old.domProfile(hc.DOMProfile), fc.domProfile(hc.DOMProfile)) ||
+		!reflect.DeepEqual(old.healthProfile(hc.HealthProfile), fc.healthProfile(hc.HealthProfile))
+}
+
+func (a *App) reloadAggregates(old, aggregates []AggregateConfig) {
+	olds := make(map[string]AggregateConfig, len(old))
+	for _, ac := range old {
+		olds[ac.Name] = ac
+	}
+	news := make(map[string]bool, len(aggregates))
+	for _, ac := range aggregates {
+		news[ac.Name] = true
+		prev, ok := olds[ac.Name]
+		if ok && reflect.DeepEqual(prev, ac) {
+			continue
+		}
+		if ok {
+			a.removeConfigAggregate(ac.Name)
+		}
+		a.addAggregate(ac, true)
+	}
+	for _, ac := range old {
+		if !news[ac.Name] {
+			a.removeConfigAggregate(ac.Name)
+		}
+	}
+}
+
+// removeConfigAggregate removes an aggregate of the config file, leaving
+// one of the same name defined while running.
+func (a *App) removeConfigAggregate(name string) {
+	for _, ag := range a.mw.aggregates {
+		if ag.Name == name && ag.fromConfig {
+			a.removeAggregate(name)
+			return
+		}
+	}
+}
+
+func (a *App) reloadAlerts(old, alerts []string) {
+	olds := make(map[string]bool, len(old))
+	for _, expr := range old {
+		olds[expr] = true
+	}
+	news := make(map[string]bool, len(alerts))
+	for _, expr := range alerts {
+		news[expr] = true
+		if olds[expr] {
+			continue
+		}
+		a.mw.addAlert(expr, true)
+	}
+	for _, expr := range old {
+		if !news[expr] {
+			a.mw.removeConfigAlert(expr)
+		}
+	}
+}
+
+// removeConfigAlert removes an alert of the config file, leaving one of
+// the same expression added while running.
+func (m *MainWidget) removeConfigAlert(expr string) {
+	for _, r := range m.alerts {
+		if r.expr == expr && r.fromConfig {
+			m.removeAlert(expr)
+			return
+		}
+	}
+}
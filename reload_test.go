@@ -0,0 +1,215 @@
+package trmon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestApp_reload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	// Polling goroutines exit right away, only the host list is tested.
+	cancel()
+	l := NewLogger(true, os.Stdout)
+	path := filepath.Join(t.TempDir(), "trmon.json")
+	a := &App{
+		hosts:        make([]*Host, 0),
+		gui:          &gocui.Gui{},
+		log:          l,
+		ctx:          ctx,
+		cancels:      make(map[*Host]context.CancelFunc),
+		community:    "public",
+		interval:     10,
+		flagInterval: 10,
+		configFile:   path,
+		fileConfig: &FileConfig{
+			Hosts: []HostConfig{
+				{Name: "127.0.0.1", Group: "tokyo"},
+				{Name: "127.0.0.2", Community: "old"},
+				{Name: "127.0.0.3"},
+			},
+			Alerts: []string{"in_bcast>1k", "in_util>90"},
+		},
+	}
+	defer a.pollers.Wait()
+	a.setProfiles(a.fileConfig)
+	a.mw = NewMainWidget("main", a.hosts, NewNarrowWidget("filter", "", l), l)
+	for _, hc := range append(a.fileConfig.Hosts, HostConfig{Name: "127.0.0.9"}) {
+		if err := a.addHost(hc); err != nil {
+			t.Fatalf("App.addHost() error = %v", err)
+		}
+	}
+	for _, expr := range a.fileConfig.Alerts {
+		if err := a.mw.addAlert(expr, true); err != nil {
+			t.Fatalf("MainWidget.addAlert() error = %v", err)
+		}
+	}
+	// an alert of the file removed and added again while running is kept
+	// when the file drops it
+	a.mw.removeAlert("in_bcast>1k")
+	for _, expr := range []string{"in_err>0", "in_bcast>1k"} {
+		if err := a.mw.addAlert(expr, false); err != nil {
+			t.Fatalf("MainWidget.addAlert() error = %v", err)
+		}
+	}
+	a.mw.Markeds = []ifKey{{"127.0.0.2", 4}}
+	unchanged := a.hosts[0]
+
+	if err := os.WriteFile(path, []byte(`{
+		"hosts": [
+			{"name": "127.0.0.1", "group": "osaka"},
+			{"name": "127.0.0.2", "community": "new"},
+			{"name": "127.0.0.4"}
+		],
+		"alerts": ["in_util>90", "out_util>90"],
+		"interval": 30,
+		"filter": "host=~core"
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.reload(a.gui); err != nil {
+		t.Fatalf("App.reload() error = %v", err)
+	}
+
+	names := make([]string, 0, len(a.mw.Hosts))
+	for _, h := range a.mw.Hosts {
+		names = append(names, h.Name)
+	}
+	if got, want := names, []string{"127.0.0.1", "127.0.0.2", "127.0.0.9", "127.0.0.4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MainWidget.Hosts = %v, want %v", got, want)
+	}
+	if a.hosts[0] != unchanged || a.hosts[0].Group != "osaka" {
+		t.Errorf("host of a new group is polled anew or not moved, group %v", a.hosts[0].Group)
+	}
+	if c := a.hosts[1].collector.(*SNMPCollector); c.params.Community != "new" {
+		t.Errorf("community of a replaced host = %v, want new", c.params.Community)
+	}
+	if len(a.mw.Markeds) != 1 {
+		t.Errorf("MainWidget.Markeds = %v, want the mark of a replaced host kept", a.mw.Markeds)
+	}
+	if got, want := a.mw.alertExprs(), []string{"in_util>90", "in_err>0", "in_bcast>1k", "out_util>90"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("alerts = %v, want %v", got, want)
+	}
+	if got, want := a.mw.runtimeAlerts(), []string{"in_err>0", "in_bcast>1k"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("alerts kept in the state file = %v, want %v", got, want)
+	}
+	if a.pollInterval().Seconds() != 30 {
+		t.Errorf("interval = %v, want 30s", a.pollInterval())
+	}
+	if a.mw.NarrowWidget.expr != "host=~core" {
+		t.Errorf("filter = %q, want host=~core", a.mw.NarrowWidget.expr)
+	}
+
+	// An unchanged file changes nothing, even what was set while running
+	a.mw.NarrowWidget.setFilter("")
+	hosts := append([]*Host(nil), a.hosts...)
+	if err := a.reload(a.gui); err != nil {
+		t.Fatalf("App.reload() error = %v", err)
+	}
+	for i := range hosts {
+		if a.hosts[i] != hosts[i] {
+			t.Errorf("host %v is polled anew", hosts[i].Name)
+		}
+	}
+	if a.mw.NarrowWidget.expr != "" {
+		t.Errorf("filter = %q, want the one set while running", a.mw.NarrowWidget.expr)
+	}
+
+	// A file giving no more interval goes back to -i
+	if err := os.WriteFile(path, []byte(`{
+		"hosts": [
+			{"name": "127.0.0.1", "group": "osaka"},
+			{"name": "127.0.0.2", "community": "new"},
+			{"name": "127.0.0.4"}
+		],
+		"alerts": ["in_util>90", "out_util>90"],
+		"filter": "host=~core"
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.reload(a.gui); err != nil {
+		t.Fatalf("App.reload() error = %v", err)
+	}
+	if a.pollInterval().Seconds() != 10 {
+		t.Errorf("interval = %v, want 10s of -i", a.pollInterval())
+	}
+
+	// An invalid file changes nothing and is reported in the title
+	if err := os.WriteFile(path, []byte(`{
+		"hosts": [{"name": "127.0.0.5"}],
+		"filter": "in_util>>50"
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a.reloadNotify(a.gui)
+	for i := range hosts {
+		if len(a.hosts) != len(hosts) || a.hosts[i] != hosts[i] {
+			t.Fatalf("MainWidget.Hosts = %v, want unchanged by an invalid file", a.mw.Hosts)
+		}
+	}
+	if got := a.mw.title(); !strings.HasPrefix(got, "reload failed: ") {
+		t.Errorf("MainWidget.title() = %q, want the reload error", got)
+	}
+
+	// Nor does a file adding what was added while running
+	for _, file := range []string{
+		`{"hosts": [{"name": "127.0.0.5"}], "alerts": ["in_err>0"]}`,
+		`{"hosts": [{"name": "127.0.0.5"}, {"name": "127.0.0.9"}]}`,
+		`{"hosts": [{"name": "127.0.0.5"}], "alerts": ["in>fast"]}`,
+	} {
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.reload(a.gui); err == nil {
+			t.Errorf("App.reload() of %v succeeds", file)
+		}
+		if len(a.hosts) != len(hosts) {
+			t.Fatalf("MainWidget.Hosts = %v, want unchanged by %v", a.mw.Hosts, file)
+		}
+	}
+
+	a.configFile = ""
+	if err := a.reload(a.gui); err == nil {
+		t.Errorf("App.reload() without configuration file succeeds")
+	}
+}
+
+func Test_hostConfigChanged(t *testing.T) {
+	old := &FileConfig{
+		DOMProfiles:    []DOMProfile{{Name: "juniper", RxPower: &DOMColumn{OID: ".1.2", Scale: 0.01}}},
+		HealthProfiles: []HealthProfile{{Name: "huawei", CPU: ".1.3"}},
+	}
+	fc := &FileConfig{
+		DOMProfiles:    []DOMProfile{{Name: "juniper", RxPower: &DOMColumn{OID: ".1.2", Scale: 0.1}}},
+		HealthProfiles: []HealthProfile{{Name: "huawei", CPU: ".1.3"}},
+	}
+	two, again, three := 2, 2, 3
+	tests := []struct {
+		name string
+		prev HostConfig
+		hc   HostConfig
+		want bool
+	}{
+		{"same", HostConfig{Name: "core1"}, HostConfig{Name: "core1"}, false},
+		{"group", HostConfig{Name: "core1", Group: "a"}, HostConfig{Name: "core1", Group: "b"}, false},
+		{"community", HostConfig{Name: "core1"}, HostConfig{Name: "core1", Community: "c"}, true},
+		{"timeout", HostConfig{Name: "core1"}, HostConfig{Name: "core1", Timeout: 5}, true},
+		{"same retries", HostConfig{Name: "core1", Retries: &two}, HostConfig{Name: "core1", Retries: &again}, false},
+		{"retries", HostConfig{Name: "core1", Retries: &two}, HostConfig{Name: "core1", Retries: &three}, true},
+		{"same profile", HostConfig{Name: "core1", HealthProfile: "huawei"}, HostConfig{Name: "core1", HealthProfile: "huawei"}, false},
+		{"changed profile", HostConfig{Name: "core1", DOMProfile: "juniper"}, HostConfig{Name: "core1", DOMProfile: "juniper"}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostConfigChanged(tt.prev, tt.hc, old, fc); got != tt.want {
+				t.Errorf("hostConfigChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package trmon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// sessionReporter is implemented by collectors keeping a session to the
+// agent, whose state is shown in the detail view.
+type sessionReporter interface {
+	session() string
+}
+
+// connect opens the UDP session to the agent unless it is open. The
+// session is kept across polls and reopened after a poll with errors.
+func (c *SNMPCollector) connect() error {
+	if c.params.Conn != nil {
+		return nil
+	}
+	if err := c.params.Connect(); err != nil {
+		c.params.Conn = nil
+		c.log.Debug().Msgf("Connect() err: %v", err)
+		return err
+	}
+	c.connectedAt = time.Now()
+	return nil
+}
+
+// disconnect closes the session so that the next poll opens a new one.
+func (c *SNMPCollector) disconnect() {
+	if c.params.Conn == nil {
+		return
+	}
+	c.params.Conn.Close()
+	c.params.Conn = nil
+}
+
+// Close closes the session. The collector connects again when polled.
+func (c *SNMPCollector) Close() error {
+	c.disconnect()
+	c.publish()
+	return nil
+}
+
+// coreWalk walks a table of IF-MIB. Its failure drops the session, unlike
+// failures of optional tables an agent may never answer.
+func (c *SNMPCollector) coreWalk(oid string, fn gosnmp.WalkFunc) error {
+	err := c.bulkWalk(oid, fn)
+	if err != nil {
+		c.stats.broken = err
+	}
+	return err
+}
+
+// endPoll keeps the session after a poll whose core walks succeeded, and
+// drops it otherwise, as its socket may be the cause.
+func (c *SNMPCollector) endPoll() {
+	defer c.publish()
+	if c.stats.broken == nil {
+		c.failedPolls = 0
+		return
+	}
+	c.failedPolls++
+	c.lastErr = c.stats.broken
+	c.log.Debug().Msgf("reconnect %v after %v failed polls: %v", c.params.Target, c.failedPolls, c.lastErr)
+	c.disconnect()
+}
+
+// publish updates the state shown by session, which is read from the UI.
+func (c *SNMPCollector) publish() {
+	var state string
+	switch {
+	case c.failedPolls > 0:
+		state = fmt.Sprintf("%v failed polls, last error: %v", c.failedPolls, c.lastErr)
+	case c.params.Conn != nil:
+		state = fmt.Sprintf("connected since %v", c.connectedAt.Format("2006-01-02 15:04:05"))
+	default:
+		state = "closed"
+	}
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+}
+
+func (c *SNMPCollector) session() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == "" {
+		return "closed"
+	}
+	return c.state
+}
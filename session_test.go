@@ -0,0 +1,88 @@
+package trmon
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSNMPCollector_session(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
+	c := NewSNMPCollector(agent.Target, "my_comm", NewLogger(false, os.Stdout))
+	c.params.Timeout = 100 * time.Millisecond
+	defer c.Close()
+
+	ifs, err := c.Discover()
+	if err != nil {
+		t.Fatalf("SNMPCollector.Discover() error = %v", err)
+	}
+	conn := c.params.Conn
+	if _, err := c.Collect(ifs); err != nil {
+		t.Fatalf("SNMPCollector.Collect() error = %v", err)
+	}
+	if c.params.Conn == nil || c.params.Conn != conn {
+		t.Errorf("the session of Discover is not kept by Collect")
+	}
+	if s := c.session(); !strings.HasPrefix(s, "connected since") {
+		t.Errorf("SNMPCollector.session() = %q", s)
+	}
+
+	// The agent stops answering
+	c.params.Community = "wrong"
+	if _, err := c.Collect(ifs); err == nil {
+		t.Errorf("SNMPCollector.Collect() error = nil, want a timeout")
+	}
+	if c.params.Conn != nil || c.failedPolls != 1 {
+		t.Errorf("Conn = %v, failedPolls = %v, want closed after 1 failed poll", c.params.Conn, c.failedPolls)
+	}
+	if s := c.session(); !strings.HasPrefix(s, "1 failed polls") {
+		t.Errorf("SNMPCollector.session() = %q", s)
+	}
+
+	c.params.Community = "my_comm"
+	if _, err := c.Collect(ifs); err != nil {
+		t.Fatalf("SNMPCollector.Collect() error = %v", err)
+	}
+	if c.params.Conn == nil || c.failedPolls != 0 {
+		t.Errorf("Conn = %v, failedPolls = %v, want reconnected", c.params.Conn, c.failedPolls)
+	}
+}
+
+func TestSNMPCollector_endPoll(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1")
+	tests := []struct {
+		name     string
+		err      error
+		core     bool
+		wantConn bool
+	}{
+		{"clean poll", nil, false, true},
+		{"optional walk timed out", errors.New("request timeout (after 0 retries)"), false, true},
+		{"core walk timed out", errors.New("request timeout (after 0 retries)"), true, false},
+		{"socket error", &net.OpError{Op: "read", Net: "udp", Err: errors.New("connection refused")}, false, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewSNMPCollector(agent.Target, "my_comm", NewLogger(false, os.Stdout))
+			defer c.Close()
+			if err := c.connect(); err != nil {
+				t.Fatalf("SNMPCollector.connect() error = %v", err)
+			}
+			c.stats = walkStats{}
+			if tt.err != nil {
+				c.fail(tt.err)
+				if tt.core {
+					c.stats.broken = tt.err
+				}
+			}
+			c.endPoll()
+			if got := c.params.Conn != nil; got != tt.wantConn {
+				t.Errorf("connected = %v, want %v, session %q", got, tt.wantConn, c.session())
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package trmon
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jroimartin/gocui"
+)
+
+// quitSignals end trmon like the quit key, so that the terminal is
+// restored and the state file is saved on the way out. reloadSignal reads
+// the configuration file again, and ends trmon like by default without one.
+var (
+	quitSignals  = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	reloadSignal = syscall.SIGHUP
+)
+
+// handleSignals quits the main loop on quitSignals, and reloads the
+// configuration on reloadSignal when there is one, until ctx is done.
+func (a *App) handleSignals(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, append(quitSignals, reloadSignal)...)
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case sig := <-sigs:
+				if a.reloads(sig) {
+					a.gui.Update(func(g *gocui.Gui) error {
+						a.reloadNotify(g)
+						return nil
+					})
+					continue
+				}
+				a.log.Info().Msgf("Received %v, so it will end the process.", sig)
+				a.quit()
+				return
+			case <-ctx.Done():
+				a.log.Debug().Msg("Done signal goroutine")
+				return
+			}
+		}
+	}()
+}
+
+// reloads reports whether sig reloads the configuration file rather than
+// ending trmon.
+func (a *App) reloads(sig os.Signal) bool {
+	return sig == reloadSignal && a.configFile != ""
+}
+
+// quit ends the main loop as if the quit key was pressed.
+func (a *App) quit() {
+	a.gui.Update(func(g *gocui.Gui) error { return gocui.ErrQuit })
+}
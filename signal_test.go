@@ -0,0 +1,28 @@
+package trmon
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestApp_reloads(t *testing.T) {
+	tests := []struct {
+		name       string
+		configFile string
+		sig        os.Signal
+		want       bool
+	}{
+		{"SIGHUP with a config file", "trmon.json", syscall.SIGHUP, true},
+		{"SIGHUP without a config file quits", "", syscall.SIGHUP, false},
+		{"SIGTERM with a config file quits", "trmon.json", syscall.SIGTERM, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{configFile: tt.configFile}
+			if got := a.reloads(tt.sig); got != tt.want {
+				t.Errorf("App.reloads(%v) = %v, want %v", tt.sig, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package trmon
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Chances per poll of the events of the simulator
+const (
+	simFlapChance  = 0.002
+	simBurstChance = 0.02
+	simStormChance = 0.001
+	simErrorChance = 0.005
+)
+
+// simSpeeds are picked at random, weighted toward access ports.
+var simSpeeds = []int64{1e9, 1e9, 1e9, 1e9, 10e9, 10e9, 25e9, 100e9}
+
+// Indexes of the counters in the order of IF.counters
+const (
+	simInOctets = iota
+	simOutOctets
+	simInUcastPkts
+	simOutUcastPkts
+	simInMcastPkts
+	simInBcastPkts
+	simOutMcastPkts
+	simOutBcastPkts
+	simInDiscards
+	simOutDiscards
+	simInError
+	simOutError
+	simCounters
+)
+
+// SimCollector fabricates the traffic of a host for demos and load tests.
+// Traffic follows a daily curve with bursts, link flaps, broadcast storms
+// and error spikes, and a few interfaces wrap their counters at 32 bits.
+type SimCollector struct {
+	name    string
+	ifs     int
+	rand    *rand.Rand
+	states  map[int]*simIF
+	last    time.Time
+	started time.Time
+	health  *Health
+	log     *Logger
+}
+
+// simIF is the traffic model and the counter values of an interface.
+type simIF struct {
+	speed int64
+	// util is the mean utilization at the daily peak in 0..1
+	util float64
+	// outRatio scales the received traffic to the sent one
+	outRatio float64
+	// phase shifts the daily curve in hours
+	phase float64
+	// wrap32 wraps the counters at 2^32 like an agent without ifXTable
+	wrap32 bool
+	// down is the number of polls left of a link flap
+	down   int
+	values [simCounters]float64
+}
+
+// NewSimCollector fabricates ifs interfaces. Collectors of the same seed
+// produce the same traffic.
+func NewSimCollector(name string, ifs int, seed int64, l *Logger) *SimCollector {
+	return &SimCollector{
+		name:   name,
+		ifs:    ifs,
+		rand:   rand.New(rand.NewSource(seed)),
+		states: make(map[int]*simIF),
+		log:    l,
+	}
+}
+
+// SimulatedHosts returns n hosts of ifs interfaces each in the group "sim",
+// or nil when n is not positive.
+func SimulatedHosts(n int, ifs int, l *Logger) []HostConfig {
+	if n <= 0 {
+		return nil
+	}
+	digits := len(fmt.Sprint(n))
+	hosts := make([]HostConfig, 0, n)
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("sim%0*d", digits, i)
+		hosts = append(hosts, HostConfig{
+			Name:      name,
+			Group:     "sim",
+			Collector: NewSimCollector(name, ifs, int64(i), l),
+		})
+	}
+	return hosts
+}
+
+// Discover fabricates the interfaces. Their traffic models are kept when
+// the host is discovered again.
+func (c *SimCollector) Discover() (map[int]*IF, error) {
+	ifs := make(map[int]*IF, c.ifs)
+	for index := 1; index <= c.ifs; index++ {
+		s, ok := c.states[index]
+		if !ok {
+			s = c.newSimIF()
+			c.states[index] = s
+		}
+		i := NewIF(index, c.log)
+		i.Desc = fmt.Sprintf("Ethernet1/%d", index)
+		i.Name = fmt.Sprintf("Eth1/%d", index)
+		i.Alias = fmt.Sprintf("%v port %d", c.name, index)
+		i.Speed = s.speed
+		i.AdminStatus = "UP"
+		i.OperStatus = "UP"
+		ifs[index] = i
+	}
+	return ifs, nil
+}
+
+func (c *SimCollector) newSimIF() *simIF {
+	s := &simIF{
+		speed: simSpeeds[c.rand.Intn(len(simSpeeds))],
+		// most ports are quiet and a few are busy
+		util:     0.02 + 0.6*math.Pow(c.rand.Float64(), 3),
+		outRatio: 0.3 + 1.4*c.rand.Float64(),
+		phase:    c.rand.Float64()*4 - 2,
+		wrap32:   c.rand.Intn(16) == 0,
+	}
+	// start anywhere so that 32 bit counters wrap soon
+	s.values[simInOctets] = c.rand.Float64() * math.MaxUint32
+	s.values[simOutOctets] = c.rand.Float64() * math.MaxUint32
+	return s
+}
+
+// Collect advances the traffic of ifs to now.
+func (c *SimCollector) Collect(ifs map[int]*IF) (map[int]*IF, error) {
+	c.collect(ifs, time.Now())
+	return ifs, nil
+}
+
+// collect advances the counters of ifs to t.
+func (c *SimCollector) collect(ifs map[int]*IF, t time.Time) {
+	dt := 0.0
+	if c.last.IsZero() {
+		c.started = t
+	} else {
+		dt = t.Sub(c.last).Seconds()
+	}
+	c.last = t
+
+	for index, i := range ifs {
+		s, ok := c.states[index]
+		if !ok {
+			continue
+		}
+		c.step(s, dt, c.daily(t, s.phase))
+		i.OperStatus = "UP"
+		if s.down > 0 {
+			i.OperStatus = "Down"
+		}
+		for n, counter := range i.counters() {
+			v := uint64(s.values[n])
+			if s.wrap32 {
+				v %= 1 << 32
+			}
+			counter.Update(int64(v), t)
+		}
+	}
+	c.health = &Health{
+		SysDescr: "trmon simulator",
+		SysName:  c.name,
+		UpTime:   t.Sub(c.started),
+		CPU:      10 + 40*c.daily(t, 0) + 5*c.rand.Float64(),
+		Memory:   40 + 10*c.daily(t, 0),
+	}
+}
+
+// daily is the load of the time of day in 0..1, peaking in the afternoon.
+func (c *SimCollector) daily(t time.Time, phase float64) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60 + phase
+	return 0.5 + 0.5*math.Sin(2*math.Pi*(hour-9)/24)
+}
+
+// step adds dt seconds of traffic to the counters of s.
+func (c *SimCollector) step(s *simIF, dt float64, daily float64) {
+	if s.down > 0 {
+		s.down--
+		return
+	}
+	if c.rand.Float64() < simFlapChance {
+		s.down = 1 + c.rand.Intn(3)
+		return
+	}
+
+	load := s.util * (0.2 + 0.8*daily) * (0.9 + 0.2*c.rand.Float64())
+	if c.rand.Float64() < simBurstChance {
+		load *= 3 + 3*c.rand.Float64()
+	}
+	load = math.Min(load, 1)
+
+	in := load * float64(s.speed) / 8 * dt
+	out := math.Min(in*s.outRatio, float64(s.speed)/8*dt)
+	for _, d := range []struct {
+		octets, ucast, mcast, bcast, discards, errors int
+		bytes                                         float64
+	}{
+		{simInOctets, simInUcastPkts, simInMcastPkts, simInBcastPkts, simInDiscards, simInError, in},
+		{simOutOctets, simOutUcastPkts, simOutMcastPkts, simOutBcastPkts, simOutDiscards, simOutError, out},
+	} {
+		// 700 bytes is a common mean packet size
+		pkts := d.bytes / 700
+		mcast := pkts * 0.01
+		bcast := pkts * 0.001
+		s.values[d.ucast] += pkts - mcast - bcast
+		if c.rand.Float64() < simStormChance {
+			bcast += 5000 * dt
+		}
+		s.values[d.octets] += d.bytes
+		s.values[d.mcast] += mcast
+		s.values[d.bcast] += bcast
+		if load > 0.9 {
+			s.values[d.discards] += pkts * 0.001
+		}
+		if c.rand.Float64() < simErrorChance {
+			s.values[d.errors] += float64(10 + c.rand.Intn(500))
+		}
+	}
+}
+
+func (c *SimCollector) Health() *Health {
+	return c.health
+}
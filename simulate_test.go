@@ -0,0 +1,50 @@
+package trmon
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSimulatedHosts(t *testing.T) {
+	l := NewLogger(false, os.Stdout)
+	hosts := SimulatedHosts(12, 4, l)
+	if len(hosts) != 12 || hosts[0].Name != "sim01" || hosts[11].Name != "sim12" || hosts[0].Group != "sim" {
+		t.Fatalf("SimulatedHosts() = %+v", hosts)
+	}
+	if hosts := SimulatedHosts(-1, 4, l); hosts != nil {
+		t.Errorf("SimulatedHosts(-1) = %+v, want nil", hosts)
+	}
+}
+
+func TestSimCollector_collect(t *testing.T) {
+	l := NewLogger(false, os.Stdout)
+	c := NewSimCollector("sim1", 64, 1, l)
+	ifs, err := c.Discover()
+	if err != nil || len(ifs) != 64 {
+		t.Fatalf("SimCollector.Discover() = %v IFs, error = %v", len(ifs), err)
+	}
+
+	t0 := time.Date(2000, time.December, 10, 15, 0, 0, 0, time.UTC)
+	c.collect(ifs, t0)
+	for n := 1; n <= 3; n++ {
+		c.collect(ifs, t0.Add(time.Duration(n)*10*time.Second))
+	}
+	var octets int64
+	for index, i := range ifs {
+		octets += i.InOctets.Diff
+		if c.states[index].wrap32 {
+			for _, counter := range i.counters() {
+				if counter.Last >= 1<<32 {
+					t.Errorf("IFs[%v].%v = %v, want wrapped at 32 bits", index, counter.name, counter.Last)
+				}
+			}
+		}
+	}
+	if octets == 0 {
+		t.Errorf("no traffic in 30 seconds")
+	}
+	if hl := c.Health(); hl == nil || hl.SysName != "sim1" || hl.UpTime <= 0 {
+		t.Errorf("SimCollector.Health() = %+v", hl)
+	}
+}
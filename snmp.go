@@ -0,0 +1,233 @@
+package trmon
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	ifDescr              string = ".3.6.1.2.1.2.2.1.2."
+	ifAlias              string = ".3.6.1.2.1.31.1.1.1.18."
+	ifName               string = ".3.6.1.2.1.31.1.1.1.1."
+	ifSpeed              string = ".3.6.1.2.1.2.2.1.5."
+	ifAdminStatus        string = ".3.6.1.2.1.2.2.1.7"
+	ifOperStatus         string = ".3.6.1.2.1.2.2.1.8"
+	ifHCInOctets         string = ".3.6.1.2.1.31.1.1.1.6."
+	ifHCOutOctets        string = ".3.6.1.2.1.31.1.1.1.10."
+	ifHCInUcastPkts      string = ".3.6.1.2.1.31.1.1.1.7."
+	ifHCOutUcastPkts     string = ".3.6.1.2.1.31.1.1.1.11."
+	ifHCInMulticastPkts  string = ".3.6.1.2.1.31.1.1.1.8."
+	ifHCInBroadcastPkts  string = ".3.6.1.2.1.31.1.1.1.9."
+	ifHCOutMulticastPkts string = ".3.6.1.2.1.31.1.1.1.12."
+	ifHCOutBroadcastPkts string = ".3.6.1.2.1.31.1.1.1.13."
+	ifInDiscards         string = ".3.6.1.2.1.2.2.1.13."
+	ifOutDiscards        string = ".3.6.1.2.1.2.2.1.19."
+	ifInErrors           string = ".3.6.1.2.1.2.2.1.14."
+	ifOutErrors          string = ".3.6.1.2.1.2.2.1.20."
+
+	ifIndex  string = ".1.3.6.1.2.1.2.2.1.1"
+	ifEntry  string = ".1.3.6.1.2.1.2.2.1"
+	ifXEntry string = ".1.3.6.1.2.1.31.1.1.1"
+
+	// IEEE8023-LAG-MIB
+	dot3adAggPortAttachedAggID string = ".1.2.840.10006.300.43.1.2.1.1.13"
+)
+
+// SNMPCollector polls IF-MIB and the optional MIBs enabled on it from an
+// SNMPv2c agent.
+type SNMPCollector struct {
+	// CDP walks CISCO-CDP-MIB in addition to LLDP-MIB
+	CDP bool
+	// Dot3 walks dot3StatsTable for the error breakdown
+	Dot3 bool
+	// Optics walks ENTITY-SENSOR-MIB, or DOMProfile when given, for DOM
+	Optics     bool
+	DOMProfile *DOMProfile
+	// HealthProfile replaces HOST-RESOURCES-MIB when given
+	HealthProfile *HealthProfile
+	// AutoTune adjusts max-repetitions after every poll
+	AutoTune bool
+
+	params      *gosnmp.GoSNMP
+	stats       walkStats
+	connectedAt time.Time
+	// failedPolls counts polls with errors in a row, the last by lastErr
+	failedPolls int
+	lastErr     error
+	// state is the session as last published by the polling goroutine
+	mu          sync.Mutex
+	state       string
+	ifs         map[int]*IF
+	health      *Health
+	healthAt    time.Time
+	lagAt       time.Time
+	neighborsAt time.Time
+	opticsAt    time.Time
+	log         *Logger
+}
+
+// NewSNMPCollector polls the target, which may be followed by a port like
+// "192.0.2.1:1161" or "[2001:db8::1]:1161".
+func NewSNMPCollector(target string, community string, l *Logger) *SNMPCollector {
+	addr, port := splitTarget(target)
+	c := &SNMPCollector{
+		params: &gosnmp.GoSNMP{
+			Target:    addr,
+			Port:      port,
+			Version:   gosnmp.Version2c,
+			Community: community,
+			Timeout:   time.Duration(3) * time.Second,
+		},
+		log: l,
+	}
+	c.params.OnRecv = c.onRecv
+	c.params.OnRetry = c.onRetry
+	return c
+}
+
+// splitTarget splits the port off the target. The port is 161 when the
+// target has none, or something else after its colon like ":local".
+func splitTarget(target string) (string, uint16) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return target, 161
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return target, 161
+	}
+	return host, uint16(p)
+}
+
+// Discover walks ifIndex.
+func (c *SNMPCollector) Discover() (map[int]*IF, error) {
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	c.stats = walkStats{}
+	defer c.endPoll()
+
+	//GET ALL Interface Index
+	c.log.Debug().Msg("Get ALL Interface Index")
+	ifs := make(map[int]*IF)
+	err := c.coreWalk(ifIndex, func(pdu gosnmp.SnmpPDU) error {
+		index := int(gosnmp.ToBigInt(pdu.Value).Int64())
+		ifs[index] = NewIF(index, c.log)
+		return nil
+	})
+	if err != nil {
+		c.log.Debug().Msgf("Failed to new IFs: %v", err)
+		return nil, err
+	}
+	return ifs, nil
+}
+
+// Collect walks the interface tables into ifs. A failed walk of an
+// optional table is only logged.
+func (c *SNMPCollector) Collect(ifs map[int]*IF) (map[int]*IF, error) {
+	if err := c.connect(); err != nil {
+		return ifs, err
+	}
+	c.ifs = ifs
+	c.stats = walkStats{}
+	defer c.endPoll()
+	if c.AutoTune {
+		defer c.tune()
+	}
+
+	c.updateHealth()
+
+	//GET ALL Interface Value
+	err := c.coreWalk(ifEntry, c.updateIFValue)
+	if err != nil {
+		c.log.Debug().Msgf("Failed to Update ifEntry: %v", err)
+	}
+	if err := c.coreWalk(ifXEntry, c.updateIFValue); err != nil {
+		c.log.Debug().Msgf("Failed to Update ifXEntry: %v", err)
+	}
+	c.updateLagMembers()
+	if c.Dot3 {
+		if err := c.bulkWalk(dot3StatsEntry, c.updateDot3Value); err != nil {
+			c.log.Debug().Msgf("Failed to Update dot3StatsEntry: %v", err)
+		}
+	}
+	if c.Optics || c.DOMProfile != nil {
+		c.updateOptics()
+	}
+	c.updateNeighbors()
+	return ifs, err
+}
+
+func (c *SNMPCollector) Health() *Health {
+	return c.health
+}
+
+// Classify retrived snmp PDU and Set new value to IF array
+func (c *SNMPCollector) updateIFValue(pdu gosnmp.SnmpPDU) error {
+	c.log.Debug().Msgf("pdu %v", pdu)
+	s := strings.Split(pdu.Name, ".")
+	index, _ := strconv.Atoi(s[len(s)-1])
+	i, ok := c.ifs[index]
+	if !ok {
+		// an interface created since the discovery
+		return nil
+	}
+	t := time.Now()
+
+	switch {
+	case strings.Contains(pdu.Name, ifDescr):
+		i.Desc = string(pdu.Value.([]byte))
+	case strings.Contains(pdu.Name, ifName):
+		i.Name = string(pdu.Value.([]byte))
+	case strings.Contains(pdu.Name, ifAlias):
+		i.Alias = string(pdu.Value.([]byte))
+	case strings.Contains(pdu.Name, ifSpeed):
+		i.Speed = gosnmp.ToBigInt(pdu.Value).Int64()
+	case strings.Contains(pdu.Name, ifAdminStatus):
+		switch gosnmp.ToBigInt(pdu.Value).Int64() {
+		case 1:
+			i.AdminStatus = "UP"
+		case 2:
+			i.AdminStatus = "Down"
+		}
+	case strings.Contains(pdu.Name, ifOperStatus):
+		switch gosnmp.ToBigInt(pdu.Value).Int64() {
+		case 1:
+			i.OperStatus = "UP"
+		case 2:
+			i.OperStatus = "Down"
+		}
+	case strings.Contains(pdu.Name, ifHCInOctets):
+		i.InOctets.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCOutOctets):
+		i.OutOctets.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCInUcastPkts):
+		i.InUcastPkts.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCOutUcastPkts):
+		i.OutUcastPkts.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCInMulticastPkts):
+		i.InMcastPkts.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCInBroadcastPkts):
+		i.InBcastPkts.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCOutMulticastPkts):
+		i.OutMcastPkts.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifHCOutBroadcastPkts):
+		i.OutBcastPkts.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifInDiscards):
+		i.InDiscards.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifOutDiscards):
+		i.OutDiscards.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifInDiscards):
+		i.InDiscards.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifOutErrors):
+		i.OutError.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	case strings.Contains(pdu.Name, ifInErrors):
+		i.InError.Update(gosnmp.ToBigInt(pdu.Value).Int64(), t)
+	}
+	return nil
+}
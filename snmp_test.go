@@ -0,0 +1,26 @@
+package trmon
+
+import "testing"
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantHost string
+		wantPort uint16
+	}{
+		{"192.0.2.1", "192.0.2.1", 161},
+		{"192.0.2.1:1161", "192.0.2.1", 1161},
+		{"core1.example.com:1161", "core1.example.com", 1161},
+		{"[2001:db8::1]:1161", "2001:db8::1", 1161},
+		{"2001:db8::1", "2001:db8::1", 161},
+		{"localhost:local", "localhost:local", 161},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			host, port := splitTarget(tt.target)
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("splitTarget() = %v, %v, want %v, %v", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package trmon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// State is the display setup restored at the next startup.
+type State struct {
+	Markeds       []ifKey  `json:"marks"`
+	Filter        string   `json:"filter"`
+	HideUnmatched bool     `json:"hide_unmatched"`
+	SortKey       string   `json:"sort_key"`
+	SortDesc      bool     `json:"sort_desc"`
+	Unit          string   `json:"unit"`
+	DisplayDownIF bool     `json:"display_down_if"`
+	Grouped       bool     `json:"grouped"`
+	Collapsed     []string `json:"collapsed"`
+	Breakdown     bool     `json:"breakdown"`
+	HostLabel     string   `json:"host_label"`
+	IFLabel       string   `json:"if_label"`
+	// Alerts and Aggregates are the ones added while running. Those of the
+	// config file are read from it again.
+	Alerts     []string          `json:"alerts"`
+	Aggregates []AggregateConfig `json:"aggregates"`
+}
+
+// DefaultStatePath returns the state file under the user's config directory,
+// or "" when the directory is unknown.
+func DefaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "trmon", "state.json")
+}
+
+func loadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(State)
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// saveState writes s through a temporary file so that a crash never
+// leaves a truncated state file behind.
+func saveState(path string, s *State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (m *MainWidget) state() *State {
+	return &State{
+		Markeds:       append([]ifKey{}, m.Markeds...),
+		Filter:        m.NarrowWidget.expr,
+		HideUnmatched: m.NarrowWidget.hide,
+		SortKey:       m.sortKey,
+		SortDesc:      m.sortDesc,
+		Unit:          m.unit.String(),
+		DisplayDownIF: m.displayDownIF,
+		Grouped:       m.grouped,
+		Collapsed:     m.collapsedKeys(),
+		Breakdown:     m.breakdown,
+		HostLabel:     m.hostLabel(),
+		IFLabel:       m.ifLabel(),
+		Alerts:        m.runtimeAlerts(),
+		Aggregates:    m.aggregateConfigs(),
+	}
+}
+
+func (m *MainWidget) collapsedKeys() []string {
+	keys := make([]string, 0, len(m.collapsed))
+	for k := range m.collapsed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// restore applies s except the filter, which is given to NewNarrowWidget
+// so that -e can take precedence.
+func (m *MainWidget) restore(s *State) {
+	m.Markeds = append(m.Markeds[:0], s.Markeds...)
+	m.NarrowWidget.hide = s.HideUnmatched
+	m.displayDownIF = s.DisplayDownIF
+	m.grouped = s.Grouped
+	m.breakdown = s.Breakdown
+	if s.HostLabel != "" {
+		if err := m.setHostLabel(s.HostLabel); err != nil {
+			m.log.Warn().Msgf("failed to restore host label: %v", err)
+		}
+	}
+	if s.IFLabel != "" {
+		if err := m.setIFLabel(s.IFLabel); err != nil {
+			m.log.Warn().Msgf("failed to restore I/F label: %v", err)
+		}
+	}
+	m.collapsed = make(map[string]bool)
+	for _, k := range s.Collapsed {
+		m.collapsed[k] = true
+	}
+	if err := m.setSort(s.SortKey, s.SortDesc); err != nil {
+		m.log.Warn().Msgf("failed to restore sort: %v", err)
+	}
+	if u, err := parseUnit(s.Unit); err == nil {
+		m.displaybps = u < Pps
+		m.setUnit(u)
+	}
+}
@@ -0,0 +1,47 @@
+package trmon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestState_saveLoad(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	m := NewMainWidget("main", nil, NewNarrowWidget("filter", "status=UP", l), l)
+	m.Markeds = []ifKey{{"core1", 513}}
+	m.NarrowWidget.hide = true
+	m.displayDownIF = false
+	m.setSort("in", true)
+	m.displaybps = false
+	m.setUnit(Kpps)
+
+	path := filepath.Join(t.TempDir(), "trmon", "state.json")
+	if err := saveState(path, m.state()); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+	st, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	if st.Filter != "status=UP" {
+		t.Errorf("State.Filter = %v, want status=UP", st.Filter)
+	}
+
+	restored := NewMainWidget("main", nil, NewNarrowWidget("filter", st.Filter, l), l)
+	restored.restore(st)
+	if !reflect.DeepEqual(restored.state(), m.state()) {
+		t.Errorf("restored state = %+v, want %+v", restored.state(), m.state())
+	}
+	if restored.displaybps {
+		t.Errorf("MainWidget.displaybps = true, want false for %v", restored.unit)
+	}
+}
+
+func TestState_loadMissing(t *testing.T) {
+	_, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if !os.IsNotExist(err) {
+		t.Errorf("loadState() error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,138 @@
+package trmon
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// DefaultMaxRepetitions is the GetBulk max-repetitions of gosnmp.
+const DefaultMaxRepetitions = 50
+
+// Bounds and step of the max-repetitions chosen by auto-tuning
+const (
+	tuneMinRepetitions  = 5
+	tuneMaxRepetitions  = 100
+	tuneStepRepetitions = 10
+)
+
+// SNMPOptions tune the requests of an SNMPCollector. Zero values keep the
+// defaults, a timeout of 3 seconds and no retries. Retries is a pointer as
+// no retries may be asked for.
+type SNMPOptions struct {
+	Timeout        time.Duration
+	Retries        *int
+	MaxRepetitions int
+	NonRepeaters   int
+	// AutoTune adjusts MaxRepetitions to the responses of the agent
+	AutoTune bool
+}
+
+// override returns o with the non-zero options of p, and Retries of p when
+// it is set.
+func (o SNMPOptions) override(p SNMPOptions) SNMPOptions {
+	if p.Timeout > 0 {
+		o.Timeout = p.Timeout
+	}
+	if p.Retries != nil {
+		o.Retries = p.Retries
+	}
+	if p.MaxRepetitions > 0 {
+		o.MaxRepetitions = p.MaxRepetitions
+	}
+	if p.NonRepeaters > 0 {
+		o.NonRepeaters = p.NonRepeaters
+	}
+	o.AutoTune = o.AutoTune || p.AutoTune
+	return o
+}
+
+func (c *SNMPCollector) SetOptions(o SNMPOptions) {
+	if o.Timeout > 0 {
+		c.params.Timeout = o.Timeout
+	}
+	if o.Retries != nil {
+		c.params.Retries = *o.Retries
+	}
+	c.params.MaxRepetitions = uint32(o.MaxRepetitions)
+	c.params.NonRepeaters = o.NonRepeaters
+	c.AutoTune = o.AutoTune
+}
+
+// walkStats is what the agent answered during a poll.
+type walkStats struct {
+	// responses are received packets and retries are resent requests
+	responses int
+	retries   int
+	// failures are walks ending with an error, and long ones are walks
+	// needing more than one response
+	failures int
+	long     int
+	// err is the last error
+	err error
+	// broken is the error of a core walk or of the socket, after which
+	// the session is reopened
+	broken error
+}
+
+// bulkWalk is BulkWalk recording the walk in c.stats.
+func (c *SNMPCollector) bulkWalk(oid string, fn gosnmp.WalkFunc) error {
+	before := c.stats.responses
+	err := c.params.BulkWalk(oid, fn)
+	if err != nil {
+		c.fail(err)
+	} else if c.stats.responses-before > 1 {
+		c.stats.long++
+	}
+	return err
+}
+
+// fail records a failed request. A failure of the socket breaks the
+// session, while the agent failing a request does not.
+func (c *SNMPCollector) fail(err error) {
+	c.stats.failures++
+	c.stats.err = err
+	var op *net.OpError
+	if errors.As(err, &op) {
+		c.stats.broken = err
+	}
+}
+
+func (c *SNMPCollector) onRecv(*gosnmp.GoSNMP) {
+	c.stats.responses++
+}
+
+func (c *SNMPCollector) onRetry(*gosnmp.GoSNMP) {
+	c.stats.retries++
+}
+
+// tune adjusts max-repetitions to the last poll. A timeout or failed walk
+// suggests responses too large for the agent or the path, so it is halved.
+// Walks needing several responses would take fewer round trips with larger
+// responses, so it grows by a step while the agent keeps up.
+func (c *SNMPCollector) tune() {
+	reps := int(c.params.MaxRepetitions)
+	if reps == 0 {
+		reps = DefaultMaxRepetitions
+	}
+	switch {
+	case c.stats.failures > 0 || c.stats.retries > 0:
+		reps /= 2
+	case c.stats.long > 0:
+		reps += tuneStepRepetitions
+	default:
+		return
+	}
+	if reps < tuneMinRepetitions {
+		reps = tuneMinRepetitions
+	}
+	if reps > tuneMaxRepetitions {
+		reps = tuneMaxRepetitions
+	}
+	if uint32(reps) != c.params.MaxRepetitions {
+		c.log.Debug().Msgf("max-repetitions of %v: %v", c.params.Target, reps)
+	}
+	c.params.MaxRepetitions = uint32(reps)
+}
@@ -0,0 +1,91 @@
+package trmon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSNMPOptions_override(t *testing.T) {
+	one, zero := 1, 0
+	global := SNMPOptions{Timeout: 3 * time.Second, Retries: &one, MaxRepetitions: 50}
+	got := global.override(SNMPOptions{Timeout: 10 * time.Second, NonRepeaters: 1, AutoTune: true})
+	want := SNMPOptions{Timeout: 10 * time.Second, Retries: &one, MaxRepetitions: 50, NonRepeaters: 1, AutoTune: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SNMPOptions.override() = %+v, want %+v", got, want)
+	}
+	// no retries of a host overrides the global retries
+	got = global.override(SNMPOptions{Retries: &zero})
+	if got.Retries == nil || *got.Retries != 0 {
+		t.Errorf("SNMPOptions.override().Retries = %v, want 0", got.Retries)
+	}
+}
+
+func TestSNMPCollector_tune(t *testing.T) {
+	tests := []struct {
+		name  string
+		reps  uint32
+		stats walkStats
+		want  uint32
+	}{
+		{
+			name:  "default grows",
+			reps:  0,
+			stats: walkStats{responses: 8, long: 2},
+			want:  60,
+		},
+		{
+			name:  "short walks keep",
+			reps:  30,
+			stats: walkStats{responses: 3},
+			want:  30,
+		},
+		{
+			name:  "retry halves",
+			reps:  40,
+			stats: walkStats{responses: 8, retries: 1, long: 2},
+			want:  20,
+		},
+		{
+			name:  "failure halves to the minimum",
+			reps:  6,
+			stats: walkStats{failures: 1},
+			want:  tuneMinRepetitions,
+		},
+		{
+			name:  "grows up to the maximum",
+			reps:  95,
+			stats: walkStats{responses: 8, long: 2},
+			want:  tuneMaxRepetitions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewSNMPCollector("192.0.2.1", "public", NewLogger(false, os.Stdout))
+			c.params.MaxRepetitions = tt.reps
+			c.stats = tt.stats
+			c.tune()
+			if c.params.MaxRepetitions != tt.want {
+				t.Errorf("MaxRepetitions = %v, want %v", c.params.MaxRepetitions, tt.want)
+			}
+		})
+	}
+}
+
+func TestSNMPCollector_Collect_autoTune(t *testing.T) {
+	agent := newTestAgent(t, "my_comm", "oids1", "oids2")
+	c := NewSNMPCollector(agent.Target, "my_comm", NewLogger(false, os.Stdout))
+	c.SetOptions(SNMPOptions{MaxRepetitions: 20, AutoTune: true})
+	ifs, err := c.Discover()
+	if err != nil {
+		t.Fatalf("SNMPCollector.Discover() error = %v", err)
+	}
+	if _, err := c.Collect(ifs); err != nil {
+		t.Fatalf("SNMPCollector.Collect() error = %v", err)
+	}
+	// ifEntry is longer than a response
+	if c.params.MaxRepetitions != 30 {
+		t.Errorf("MaxRepetitions = %v, want 30", c.params.MaxRepetitions)
+	}
+}
@@ -1,9 +1,13 @@
 package trmon
 
 import (
+	"encoding/csv"
 	"fmt"
-	"regexp"
+	"io"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
@@ -18,9 +22,31 @@ const (
 	u: toggle the unit of bps or pps [][k][m]
 	d: toggle the display of Down I/F
 	p: toggle the display of bps or pps
-	/: narrow down with regex
-	   Targets of narrowing down are Description and I/F
+	b: toggle splitting pps into unicast/multicast/broadcast
+	/: narrow down with a filter expression
+	   field predicates joined with and, or, not and ( )
+	     host=~core.* and in_util>50 and not status=Down
+	   a bare regexp matches I/F and Description, also one like vlan=10
+	f: toggle highlighting or hiding lines not matching the filter
 	Enter: mark that line. Or unmark.
+	       On a group or host header, collapse or expand it.
+	g: toggle grouping hosts into collapsible sections
+	   host headers show CPU, memory and uptime
+	i: show the detail of that line. q, i or Esc closes it
+	a: add a host "[group/]<agent> [community]"
+	x: remove the host of that line
+	r: reload the configuration file, also on SIGHUP
+	:: command line. Tab completes, ↑/↓ recall history
+	   sort [column] [asc|desc]    interval <sec>
+	   add [group/]<agent> [comm]  remove <agent>
+	   unit <bps|kbps|...|mpps>    export <file.csv>
+	   filter [expr]               filtermode <highlight|hide>
+	   group <on|off>              mark <all|none>
+	   aggregate <name>            unaggregate <name>
+	   breakdown <on|off>          alert <expr>
+	   unalert [expr]              hostname <target|sysname>
+	   ifname <descr|name>         reload
+	   help                        quit
 
 	k, ↑: up cursor
 	j, ↓: down cursor
@@ -58,13 +84,32 @@ func (u Unit) String() string {
 	return ""
 }
 
+func parseUnit(s string) (Unit, error) {
+	for _, u := range []Unit{Bps, Kbps, Mbps, Pps, Kpps, Mpps} {
+		if u.String() == s {
+			return u, nil
+		}
+	}
+	return Bps, fmt.Errorf("Unknown unit %v", s)
+}
+
 type UnitCalc func(int64) int64
 
-type marked struct {
-	Host string
-	IF   string
+// sortKeys are the column names accepted by setSort.
+var sortKeys = []string{"name", "if", "stat", "in", "out", "inerr", "outerr", "indis", "outdis", "neighbor", "desc"}
+
+// ifKey identifies an interface independently of how it is displayed.
+type ifKey struct {
+	Host  string `json:"host"`
+	Index int    `json:"index"`
 }
 
+// headerLines is the number of table lines above the first row.
+const headerLines = 2
+
+// noticeDuration is how long a notice stays in the title of the table
+const noticeDuration = 10 * time.Second
+
 type MainWidget struct {
 	Name          string
 	Hosts         []*Host
@@ -73,14 +118,61 @@ type MainWidget struct {
 	unit          Unit
 	unitCalc      UnitCalc
 	log           *Logger
-	Markeds       []marked
+	Markeds       []ifKey
+	sortKey       string
+	sortDesc      bool
+	// displayed is the lines in the printed order
+	displayed []tableLine
+	// detail is the interface shown in the detail view, if any
+	detail *ifKey
+	// grouped shows hosts in collapsible group sections
+	grouped    bool
+	collapsed  map[string]bool
+	aggregates []*Aggregate
+	// breakdown splits packet rates into unicast, multicast and broadcast
+	breakdown bool
+	// useSysName and useIfName choose the labels of the Name and I/F columns
+	useSysName bool
+	useIfName  bool
+	alerts     []alertRule
+	thresholds OpticThresholds
+	// lags is the LAG aggregators found at the last print
+	lags map[ifKey]bool
+	// status is shown as the title of the table when given
+	status func() string
+	// notice is shown after the status for noticeDuration since noticeAt
+	notice   string
+	noticeAt time.Time
+	// fast is the interfaces of the fast tier as of the last draw
+	fast *fastTargets
 	*NarrowWidget
 }
 
+// ifRow is an interface line of the table.
+type ifRow struct {
+	host *Host
+	ifc  *IF
+}
+
+func (r ifRow) key() ifKey {
+	return ifKey{r.host.Name, r.ifc.Index}
+}
+
+// tableLine is a printed line. ifc is nil for a host header or a line of an
+// unreachable host, and host is nil too for a group header.
+type tableLine struct {
+	ifRow
+	group string
+}
+
 type NarrowWidget struct {
 	Name   string
-	regexp *regexp.Regexp
-	log    *Logger
+	expr   string
+	filter filterFunc
+	// hide hides unmatched lines instead of only highlighting matched ones
+	hide bool
+	err  error
+	log  *Logger
 }
 
 type Editor struct{}
@@ -92,6 +184,9 @@ func NewMainWidget(name string, hosts []*Host, nw *NarrowWidget, l *Logger) *Mai
 		displayDownIF: true,
 		displaybps:    true,
 		NarrowWidget:  nw,
+		collapsed:     make(map[string]bool),
+		thresholds:    DefaultOpticThresholds,
+		fast:          new(fastTargets),
 		log:           l,
 	}
 	if err := m.setUnit(Bps); err != nil {
@@ -121,6 +216,24 @@ func (m *MainWidget) setUnit(unit Unit) error {
 	return nil
 }
 
+// notify shows msg in the title of the table for a while, for actions
+// without a line of their own to report on.
+func (m *MainWidget) notify(msg string) {
+	m.notice = msg
+	m.noticeAt = time.Now()
+}
+
+func (m *MainWidget) title() string {
+	s := make([]string, 0, 2)
+	if m.status != nil {
+		s = append(s, m.status())
+	}
+	if m.notice != "" && time.Since(m.noticeAt) < noticeDuration {
+		s = append(s, m.notice)
+	}
+	return strings.Join(s, " | ")
+}
+
 func (m *MainWidget) Layout(g *gocui.Gui) error {
 	maxX, maxY := g.Size()
 	v, err := g.SetView(m.Name, 0, 0, maxX-2, maxY-3)
@@ -132,13 +245,83 @@ func (m *MainWidget) Layout(g *gocui.Gui) error {
 	v.Clear()
 	v.Highlight = true
 	v.SelBgColor = gocui.ColorMagenta
+	for _, h := range m.Hosts {
+		h.apply()
+	}
+	v.Title = m.title()
+	m.updateAggregates()
+	m.fast.set(m.fastKeys())
 	m.print(v)
-	return nil
+	return m.layoutDetail(g)
 }
 
 func (m *MainWidget) print(v *gocui.View) {
-	t := newViewTable(v, m.unit.String())
+	t := newViewTable(v, m.unitLabel())
+	marked, narrowed, other, unreachable := m.rows()
+	m.displayed = m.displayed[:0]
+	m.lags = lagAggregators(m.Hosts)
+
+	if m.grouped {
+		m.printGrouped(t, marked, narrowed, other)
+		t.Render()
+		return
+	}
+	// Set Row to TableView
+	m.addRows(t, nestLags(colorRows(marked, narrowed, other)))
+	for _, h := range unreachable {
+		m.addLine(t, tableLine{ifRow: ifRow{h, nil}}, unreachableRow(h), tablewriter.Colors{tablewriter.FgRedColor})
+	}
 
+	t.Render()
+}
+
+// colorRows colors marked, narrowed and other rows keeping their order.
+func colorRows(marked, narrowed, other []ifRow) []coloredRow {
+	colored := make([]coloredRow, 0, len(marked)+len(narrowed)+len(other))
+	for _, c := range []struct {
+		rows  []ifRow
+		color int
+	}{
+		{marked, tablewriter.FgYellowColor},
+		{narrowed, tablewriter.FgCyanColor},
+		{other, tablewriter.FgWhiteColor},
+	} {
+		for _, r := range c.rows {
+			colored = append(colored, coloredRow{ifRow: r, color: c.color})
+		}
+	}
+	return colored
+}
+
+func (m *MainWidget) addRows(t *tablewriter.Table, rows []coloredRow) {
+	for _, r := range rows {
+		row := m.formatRow(r.ifRow)
+		if r.nested {
+			row[1] = "└ " + row[1]
+		}
+		if m.lags[r.key()] && r.host.lagImbalanced(r.ifc.Index) {
+			row[1] += " (imbalanced)"
+		}
+		if m.thresholds.lowLight(r.ifc.Optics) {
+			row[1] += " (low light)"
+		}
+		color := r.color
+		if len(m.alerting(r.ifRow)) > 0 {
+			color = tablewriter.FgRedColor
+		}
+		m.addLine(t, tableLine{ifRow: r.ifRow}, row, tablewriter.Colors{color})
+	}
+}
+
+// addLine adds a line to the table and remembers what it shows.
+func (m *MainWidget) addLine(t *tablewriter.Table, l tableLine, row []string, colors tablewriter.Colors) {
+	m.displayed = append(m.displayed, l)
+	setRowToTable(t, row, colors)
+}
+
+// rows classifies displayed interfaces into marked, narrowed and other in
+// display order, and returns hosts still waiting for retry separately.
+func (m *MainWidget) rows() (marked, narrowed, other []ifRow, unreachable []*Host) {
 	//Always be in the same order of display
 	var keys []int
 	for k := range m.Hosts {
@@ -146,19 +329,21 @@ func (m *MainWidget) print(v *gocui.View) {
 	}
 	sort.Ints(keys)
 
-	marked := make([][]string, 0, 300)
-	narrowed := make([][]string, 0, 300)
-	other := make([][]string, 0, 300)
+	marked = make([]ifRow, 0, 300)
+	narrowed = make([]ifRow, 0, 300)
+	other = make([]ifRow, 0, 300)
 
 	for _, k := range keys {
+		if !m.Hosts[k].Reachable {
+			unreachable = append(unreachable, m.Hosts[k])
+			continue
+		}
 		m.classify(&marked, &narrowed, &other, m.Hosts[k])
 	}
-	// Set Row to TableView
-	setRowToTable(t, marked, tablewriter.FgYellowColor)
-	setRowToTable(t, narrowed, tablewriter.FgCyanColor)
-	setRowToTable(t, other, tablewriter.FgWhiteColor)
-
-	t.Render()
+	m.sortRows(marked)
+	m.sortRows(narrowed)
+	m.sortRows(other)
+	return marked, narrowed, other, unreachable
 }
 
 func newViewTable(v *gocui.View, unit string) *tablewriter.Table {
@@ -167,7 +352,18 @@ func newViewTable(v *gocui.View, unit string) *tablewriter.Table {
 	t.SetBorder(false)
 	t.SetAutoWrapText(false)
 	t.SetAutoFormatHeaders(false)
-	t.SetHeader([]string{
+	header := tableHeader(unit)
+	t.SetHeader(header)
+	colors := make([]tablewriter.Colors, len(header))
+	for i := range colors {
+		colors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor}
+	}
+	t.SetHeaderColor(colors...)
+	return t
+}
+
+func tableHeader(unit string) []string {
+	return []string{
 		"Name",
 		"I/F",
 		"Stat",
@@ -177,41 +373,34 @@ func newViewTable(v *gocui.View, unit string) *tablewriter.Table {
 		"OutErr",
 		"InDis",
 		"OutDis",
+		"Neighbor",
 		"Description",
-	})
-	t.SetHeaderColor(
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor, tablewriter.FgBlackColor},
-	)
-	return t
+	}
 }
 
-func setRowToTable(t *tablewriter.Table, rows [][]string, color int) {
-	for _, row := range rows {
-		t.Rich(row, []tablewriter.Colors{
-			{color},
-			{color},
-			{color},
-			{color},
-			{color},
-			{color},
-			{color},
-			{color},
-			{color},
-			{color},
-		})
+func setRowToTable(t *tablewriter.Table, row []string, colors tablewriter.Colors) {
+	cs := make([]tablewriter.Colors, len(row))
+	for i := range cs {
+		cs[i] = colors
+	}
+	t.Rich(row, cs)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
 	}
+	return false
 }
 
-func (m *MainWidget) classify(marked *[][]string, narrowed *[][]string, other *[][]string, h *Host) {
+// unreachableRow is a placeholder line for a host still waiting for retry.
+func unreachableRow(h *Host) []string {
+	return []string{h.Name, "-", "Unreachable", "-", "-", "-", "-", "-", "-", "", ""}
+}
+
+func (m *MainWidget) classify(marked *[]ifRow, narrowed *[]ifRow, other *[]ifRow, h *Host) {
 	var keys []int
 	for k := range h.IFs {
 		keys = append(keys, k)
@@ -223,45 +412,224 @@ func (m *MainWidget) classify(marked *[][]string, narrowed *[][]string, other *[
 		if !m.displayDownIF && h.IFs[k].OperStatus == "Down" {
 			continue
 		}
-		// toggle display bps or pps
-		in := h.IFs[k].InOctets.Rate
-		out := h.IFs[k].OutOctets.Rate
-		if !m.displaybps {
-			in = h.IFs[k].InUcastPkts.Rate
-			out = h.IFs[k].OutUcastPkts.Rate
+		row := ifRow{h, h.IFs[k]}
+		// Classify Line
+		if m.isMarked(row.key()) {
+			*marked = append(*marked, row)
+			continue
+		}
+		if m.NarrowWidget.filter(row) {
+			*narrowed = append(*narrowed, row)
+		} else if !m.NarrowWidget.hide || m.NarrowWidget.expr == "" {
+			*other = append(*other, row)
 		}
+	}
+}
 
-		data := []string{
-			h.Name,
-			h.IFs[k].Desc,
-			h.IFs[k].OperStatus,
-			humanize.Comma(m.unitCalc(in)),
-			humanize.Comma(m.unitCalc(out)),
-			humanize.Comma(h.IFs[k].InError.Diff),
-			humanize.Comma(h.IFs[k].OutError.Diff),
-			humanize.Comma(h.IFs[k].InDiscards.Diff),
-			humanize.Comma(h.IFs[k].OutDiscards.Diff),
-			h.IFs[k].Alias,
+func (m *MainWidget) isMarked(key ifKey) bool {
+	for _, v := range m.Markeds {
+		if v == key {
+			return true
 		}
-		// Classify Line
-		hit := false
-		for _, v := range m.Markeds {
-			if v.Host == h.Name && v.IF == h.IFs[k].Desc {
-				*marked = append(*marked, data)
-				hit = true
-				continue
-			}
+	}
+	return false
+}
+
+// toggleMark marks the interface, or unmarks it if already marked.
+func (m *MainWidget) toggleMark(key ifKey) {
+	for i, v := range m.Markeds {
+		if v == key {
+			m.log.Debug().Msgf("delete marked host %v, if %v", key.Host, key.Index)
+			m.Markeds = append(m.Markeds[:i], m.Markeds[i+1:]...)
+			return
 		}
-		if hit {
+	}
+	m.log.Debug().Msgf("mark host %v, if %v", key.Host, key.Index)
+	m.Markeds = append(m.Markeds, key)
+}
+
+// rowAt returns the line under the cursor of the view.
+func (m *MainWidget) rowAt(v *gocui.View) (tableLine, bool) {
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	i := oy + cy - headerLines
+	if i < 0 || i >= len(m.displayed) {
+		return tableLine{}, false
+	}
+	return m.displayed[i], true
+}
+
+// lookup finds the current row of the interface.
+func (m *MainWidget) lookup(key ifKey) (ifRow, bool) {
+	for _, h := range m.Hosts {
+		if h.Name != key.Host {
 			continue
 		}
-		s := fmt.Sprintf("%v %v", h.IFs[k].Desc, h.IFs[k].Alias)
-		if m.NarrowWidget.regexp.MatchString(s) {
-			*narrowed = append(*narrowed, data)
-		} else {
-			*other = append(*other, data)
+		if i, ok := h.IFs[key.Index]; ok {
+			return ifRow{h, i}, true
 		}
 	}
+	return ifRow{}, false
+}
+
+// rates returns IN and OUT rate of the unit currently displayed.
+func (m *MainWidget) rates(i *IF) (int64, int64) {
+	// toggle display bps or pps
+	if !m.displaybps {
+		return m.unitCalc(i.inPkts()), m.unitCalc(i.outPkts())
+	}
+	return m.unitCalc(i.InOctets.Rate), m.unitCalc(i.OutOctets.Rate)
+}
+
+// breakdownOn reports whether packet rates are shown as ucast/mcast/bcast.
+func (m *MainWidget) breakdownOn() bool {
+	return m.breakdown && !m.displaybps
+}
+
+// unitLabel is the unit in the IN and OUT headers.
+func (m *MainWidget) unitLabel() string {
+	if m.breakdownOn() {
+		return m.unit.String() + " u/m/b"
+	}
+	return m.unit.String()
+}
+
+// breakdownCell formats packet rates as "ucast/mcast/bcast".
+func (m *MainWidget) breakdownCell(ucast, mcast, bcast *Counter) string {
+	return fmt.Sprintf("%v/%v/%v",
+		humanize.Comma(m.unitCalc(ucast.Rate)),
+		humanize.Comma(m.unitCalc(mcast.Rate)),
+		humanize.Comma(m.unitCalc(bcast.Rate)))
+}
+
+func (m *MainWidget) formatRow(r ifRow) []string {
+	in, out := m.rates(r.ifc)
+	inCell, outCell := humanize.Comma(in), humanize.Comma(out)
+	if m.breakdownOn() {
+		inCell = m.breakdownCell(r.ifc.InUcastPkts, r.ifc.InMcastPkts, r.ifc.InBcastPkts)
+		outCell = m.breakdownCell(r.ifc.OutUcastPkts, r.ifc.OutMcastPkts, r.ifc.OutBcastPkts)
+	}
+	return []string{
+		m.hostName(r.host),
+		m.ifName(r.ifc),
+		r.ifc.OperStatus,
+		inCell,
+		outCell,
+		humanize.Comma(r.ifc.InError.Diff),
+		humanize.Comma(r.ifc.OutError.Diff),
+		humanize.Comma(r.ifc.InDiscards.Diff),
+		humanize.Comma(r.ifc.OutDiscards.Diff),
+		r.ifc.neighbor(),
+		r.ifc.Alias,
+	}
+}
+
+// setSort sorts rows by the column key within each class of rows.
+// An empty key restores the host and ifIndex order.
+func (m *MainWidget) setSort(key string, desc bool) error {
+	if key != "" && !contains(sortKeys, key) {
+		return fmt.Errorf("Unknown sort key %v", key)
+	}
+	m.sortKey = key
+	m.sortDesc = desc
+	return nil
+}
+
+func (m *MainWidget) sortRows(rows []ifRow) {
+	if m.sortKey == "" {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if m.sortDesc {
+			return m.less(rows[j], rows[i])
+		}
+		return m.less(rows[i], rows[j])
+	})
+}
+
+func (m *MainWidget) less(a, b ifRow) bool {
+	ain, aout := m.rates(a.ifc)
+	bin, bout := m.rates(b.ifc)
+	switch m.sortKey {
+	case "name":
+		return m.hostName(a.host) < m.hostName(b.host)
+	case "if":
+		return m.ifName(a.ifc) < m.ifName(b.ifc)
+	case "stat":
+		return a.ifc.OperStatus < b.ifc.OperStatus
+	case "in":
+		return ain < bin
+	case "out":
+		return aout < bout
+	case "inerr":
+		return a.ifc.InError.Diff < b.ifc.InError.Diff
+	case "outerr":
+		return a.ifc.OutError.Diff < b.ifc.OutError.Diff
+	case "indis":
+		return a.ifc.InDiscards.Diff < b.ifc.InDiscards.Diff
+	case "outdis":
+		return a.ifc.OutDiscards.Diff < b.ifc.OutDiscards.Diff
+	case "neighbor":
+		return a.ifc.neighbor() < b.ifc.neighbor()
+	case "desc":
+		return a.ifc.Alias < b.ifc.Alias
+	}
+	return false
+}
+
+// markAll marks every displayed interface.
+func (m *MainWidget) markAll() {
+	markedRows, narrowed, other, _ := m.rows()
+	for _, rows := range [][]ifRow{markedRows, narrowed, other} {
+		for _, r := range rows {
+			if !m.isMarked(r.key()) {
+				m.Markeds = append(m.Markeds, r.key())
+			}
+		}
+	}
+}
+
+// export writes displayed interfaces as CSV in display order.
+func (m *MainWidget) export(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(tableHeader(m.unit.String())); err != nil {
+		return err
+	}
+	marked, narrowed, other, _ := m.rows()
+	for _, rows := range [][]ifRow{marked, narrowed, other} {
+		for _, r := range rows {
+			in, out := m.rates(r.ifc)
+			record := []string{
+				m.hostName(r.host),
+				m.ifName(r.ifc),
+				r.ifc.OperStatus,
+				strconv.FormatInt(in, 10),
+				strconv.FormatInt(out, 10),
+				strconv.FormatInt(r.ifc.InError.Diff, 10),
+				strconv.FormatInt(r.ifc.OutError.Diff, 10),
+				strconv.FormatInt(r.ifc.InDiscards.Diff, 10),
+				strconv.FormatInt(r.ifc.OutDiscards.Diff, 10),
+				r.ifc.neighbor(),
+				r.ifc.Alias,
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// unmarkHost drops every mark of the host.
+func (m *MainWidget) unmarkHost(host string) {
+	markeds := m.Markeds[:0]
+	for _, v := range m.Markeds {
+		if v.Host != host {
+			markeds = append(markeds, v)
+		}
+	}
+	m.Markeds = markeds
 }
 
 func NewNarrowWidget(name string, expr string, l *Logger) *NarrowWidget {
@@ -269,26 +637,48 @@ func NewNarrowWidget(name string, expr string, l *Logger) *NarrowWidget {
 		Name: name,
 		log:  l,
 	}
-	if err := n.setRegexp(expr); err != nil {
-		n.log.Warn().Msgf("failed setRegexp %v", err)
-		return nil
+	n.filter, _ = parseFilter("")
+	if err := n.setFilter(expr); err != nil {
+		n.log.Warn().Msgf("failed setFilter %v", err)
 	}
 	return n
 }
 
-func (n *NarrowWidget) setRegexp(expr string) error {
-	//Nothing is given, Nothing matches.
-	if expr == "" {
-		expr = "$^"
-	}
-	r, err := regexp.Compile(expr)
+// setFilter compiles expr. An invalid expression keeps the current filter
+// and is reported in the title of the input line.
+func (n *NarrowWidget) setFilter(expr string) error {
+	f, err := parseFilter(expr)
+	n.err = err
 	if err != nil {
 		return err
 	}
-	n.regexp = r
+	n.expr = expr
+	n.filter = f
+	return nil
+}
+
+// setFilterView sets the filter and shows expr in the input line.
+func setFilterView(g *gocui.Gui, n *NarrowWidget, expr string) error {
+	if err := n.setFilter(expr); err != nil {
+		return err
+	}
+	if v, err := g.View(n.Name); err == nil {
+		v.Clear()
+		fmt.Fprint(v, expr)
+	}
 	return nil
 }
 
+func (n *NarrowWidget) title() string {
+	if n.err != nil {
+		return fmt.Sprintf("filter error: %v", n.err)
+	}
+	if n.hide {
+		return "filter (hide others)"
+	}
+	return "filter (highlight)"
+}
+
 func (w *NarrowWidget) Layout(g *gocui.Gui) error {
 	maxX, maxY := g.Size()
 	v, err := g.SetView(w.Name, 0, maxY-2, maxX-2, maxY)
@@ -297,6 +687,7 @@ func (w *NarrowWidget) Layout(g *gocui.Gui) error {
 			return err
 		}
 	}
+	v.Title = w.title()
 	v.Editable = true
 	v.Editor = &Editor{}
 	return nil
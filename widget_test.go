@@ -1,7 +1,8 @@
 package trmon
 
 import (
-	"regexp"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -58,10 +59,9 @@ func TestMainWidget_setUnit(t *testing.T) {
 	}
 }
 
-func TestNarrowWidget_setRegexp(t *testing.T) {
+func TestNarrowWidget_setFilter(t *testing.T) {
 	type fields struct {
-		Name   string
-		regexp *regexp.Regexp
+		Name string
 	}
 	type args struct {
 		expr string
@@ -89,16 +89,125 @@ func TestNarrowWidget_setRegexp(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:   "Field predicates",
+			fields: fields{},
+			args: args{
+				expr: "host=~core.* and in_util>50 and not status=Down",
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Unknown field with an invalid regexp",
+			fields: fields{},
+			args: args{
+				expr: "hoge>(",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			n := &NarrowWidget{
-				Name:   tt.fields.Name,
-				regexp: tt.fields.regexp,
+				Name: tt.fields.Name,
+			}
+			if err := n.setFilter(tt.args.expr); (err != nil) != tt.wantErr {
+				t.Errorf("NarrowWidget.setFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (n.err != nil) != tt.wantErr {
+				t.Errorf("NarrowWidget.err = %v, wantErr %v", n.err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func testHost(name string, l *Logger) *Host {
+	h := newHost(name, "", l)
+	h.Reachable = true
+	for i, desc := range []string{"eth0", "eth1", "eth2"} {
+		ifc := NewIF(i+1, l)
+		ifc.Desc = desc
+		ifc.OperStatus = "UP"
+		ifc.InOctets.Rate = int64(10 * (i + 1))
+		ifc.OutOctets.Rate = int64(30 - 10*i)
+		h.IFs[i+1] = ifc
+	}
+	return h
+}
+
+func TestMainWidget_export(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	tests := []struct {
+		name     string
+		sortKey  string
+		sortDesc bool
+		markeds  []ifKey
+		want     string
+	}{
+		{
+			name: "ifIndex order",
+			want: `Name,I/F,Stat,IN[bps],OUT[bps],InErr,OutErr,InDis,OutDis,Neighbor,Description
+host1,eth0,UP,80,240,0,0,0,0,,
+host1,eth1,UP,160,160,0,0,0,0,,
+host1,eth2,UP,240,80,0,0,0,0,,
+`,
+		},
+		{
+			name:     "sort by in desc",
+			sortKey:  "in",
+			sortDesc: true,
+			want: `Name,I/F,Stat,IN[bps],OUT[bps],InErr,OutErr,InDis,OutDis,Neighbor,Description
+host1,eth2,UP,240,80,0,0,0,0,,
+host1,eth1,UP,160,160,0,0,0,0,,
+host1,eth0,UP,80,240,0,0,0,0,,
+`,
+		},
+		{
+			name:    "marked first then sort by out",
+			sortKey: "out",
+			markeds: []ifKey{{"host1", 1}},
+			want: `Name,I/F,Stat,IN[bps],OUT[bps],InErr,OutErr,InDis,OutDis,Neighbor,Description
+host1,eth0,UP,80,240,0,0,0,0,,
+host1,eth2,UP,240,80,0,0,0,0,,
+host1,eth1,UP,160,160,0,0,0,0,,
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMainWidget("main", []*Host{testHost("host1", l)}, NewNarrowWidget("regexp", "", l), l)
+			m.Markeds = tt.markeds
+			if err := m.setSort(tt.sortKey, tt.sortDesc); err != nil {
+				t.Fatalf("MainWidget.setSort() error = %v", err)
 			}
-			if err := n.setRegexp(tt.args.expr); (err != nil) != tt.wantErr {
-				t.Errorf("NarrowWidget.setRegexp() error = %v, wantErr %v", err, tt.wantErr)
+			var b strings.Builder
+			if err := m.export(&b); err != nil {
+				t.Fatalf("MainWidget.export() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("MainWidget.export() = %v, want %v", b.String(), tt.want)
 			}
 		})
 	}
 }
+
+func TestMainWidget_toggleMark(t *testing.T) {
+	l := NewLogger(true, os.Stdout)
+	h := testHost("host1", l)
+	// Same description on two interfaces must not share a mark
+	h.IFs[2].Desc = h.IFs[1].Desc
+	m := NewMainWidget("main", []*Host{h}, NewNarrowWidget("filter", "", l), l)
+
+	m.toggleMark(ifKey{"host1", 2})
+	marked, _, other, _ := m.rows()
+	if len(marked) != 1 || marked[0].ifc != h.IFs[2] || len(other) != 2 {
+		t.Errorf("MainWidget.rows() marked = %v, other = %v, want only ifIndex 2 marked", marked, other)
+	}
+	if r, ok := m.lookup(ifKey{"host1", 2}); !ok || r.ifc != h.IFs[2] {
+		t.Errorf("MainWidget.lookup() = %v, %v, want ifIndex 2", r, ok)
+	}
+	m.toggleMark(ifKey{"host1", 2})
+	if len(m.Markeds) != 0 {
+		t.Errorf("MainWidget.Markeds = %v, want unmarked", m.Markeds)
+	}
+}